@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
 	"trpc.group/trpc-go/trpc-go/filter"
 	"trpc.group/trpc-go/trpc-go/plugin"
 )
@@ -36,10 +37,72 @@ func init() {
 	plugin.Register(PluginName, &serviceMethodFiltersPlugin{})
 }
 
-type methodClientFilters map[string][]filter.ClientFilter
-type methodServerFilters map[string][]filter.ServerFilter
-type serviceMethodClientFilters map[string]map[string][]filter.ClientFilter
-type serviceMethodServerFilters map[string]map[string][]filter.ServerFilter
+// methodClientFilters 保存一个 service 下各个 method 的 client filter。
+// methods 以 method 名为 key，routes 保存按配置顺序排列的 HTTP 路由。
+type methodClientFilters struct {
+	methods map[string][]filter.ClientFilter
+	routes  []clientRouteFilters
+}
+
+type clientRouteFilters struct {
+	route   *httpRoute
+	filters []filter.ClientFilter
+}
+
+// methodServerFilters 保存一个 service 下各个 method 的 server filter。
+// methods 以 method 名为 key，routes 保存按配置顺序排列的 HTTP 路由。
+type methodServerFilters struct {
+	methods map[string][]filter.ServerFilter
+	routes  []serverRouteFilters
+}
+
+type serverRouteFilters struct {
+	route   *httpRoute
+	filters []filter.ServerFilter
+}
+
+type serviceMethodClientFilters map[string]*methodClientFilters
+type serviceMethodServerFilters map[string]*methodServerFilters
+
+// lookup 先按 method 名精确匹配，再按 HTTP 方法和路径匹配路由。
+func (mf *methodClientFilters) lookup(msg codec.Msg) ([]filter.ClientFilter, bool) {
+	if filters, ok := mf.methods[msg.CalleeMethod()]; ok {
+		return filters, true
+	}
+	if len(mf.routes) == 0 {
+		return nil, false
+	}
+	method, path, ok := clientHTTPRoute(msg)
+	if !ok {
+		return nil, false
+	}
+	for _, r := range mf.routes {
+		if r.route.match(method, path) {
+			return r.filters, true
+		}
+	}
+	return nil, false
+}
+
+// lookup 先按 method 名精确匹配，再按 HTTP 方法和路径匹配路由。
+func (mf *methodServerFilters) lookup(ctx context.Context, msg codec.Msg) ([]filter.ServerFilter, bool) {
+	if filters, ok := mf.methods[msg.CalleeMethod()]; ok {
+		return filters, true
+	}
+	if len(mf.routes) == 0 {
+		return nil, false
+	}
+	method, path, ok := serverHTTPRoute(ctx)
+	if !ok {
+		return nil, false
+	}
+	for _, r := range mf.routes {
+		if r.route.match(method, path) {
+			return r.filters, true
+		}
+	}
+	return nil, false
+}
 
 type serviceMethodFiltersPlugin struct {
 	client serviceMethodClientFilters
@@ -78,10 +141,8 @@ func newClientIntercept(
 ) filter.ClientFilter {
 	return func(ctx context.Context, req, rsp interface{}, handler filter.ClientHandleFunc) error {
 		msg := trpc.Message(ctx)
-		service := msg.CalleeServiceName()
-		method := msg.CalleeMethod()
-		if methodFilters, ok := serviceFilters[service]; ok {
-			if filters, ok := methodFilters[method]; ok {
+		if methodFilters, ok := serviceFilters[msg.CalleeServiceName()]; ok {
+			if filters, ok := methodFilters.lookup(msg); ok {
 				return filter.ClientChain(filters).Filter(ctx, req, rsp, handler)
 			}
 		}
@@ -94,10 +155,8 @@ func newServerIntercept(
 ) filter.ServerFilter {
 	return func(ctx context.Context, req interface{}, handler filter.ServerHandleFunc) (interface{}, error) {
 		msg := trpc.Message(ctx)
-		service := msg.CalleeServiceName()
-		method := msg.CalleeMethod()
-		if methodFilters, ok := serviceFilters[service]; ok {
-			if filters, ok := methodFilters[method]; ok {
+		if methodFilters, ok := serviceFilters[msg.CalleeServiceName()]; ok {
+			if filters, ok := methodFilters.lookup(ctx, msg); ok {
 				return filter.ServerChain(filters).Filter(ctx, req, handler)
 			}
 		}
@@ -124,13 +183,21 @@ func loadClientFilters(
 
 	smf := make(serviceMethodClientFilters, len(services))
 	for _, service := range services {
-		mf := make(methodClientFilters, len(service.Methods))
+		mf := &methodClientFilters{methods: make(map[string][]filter.ClientFilter, len(service.Methods))}
 		for _, method := range service.Methods {
 			f, err := loadMethodFilters(method.Filters)
 			if err != nil {
 				return nil, err
 			}
-			mf[method.Name] = f
+			route, isRoute, err := parseHTTPRoute(method.Name)
+			if err != nil {
+				return nil, err
+			}
+			if isRoute {
+				mf.routes = append(mf.routes, clientRouteFilters{route: route, filters: f})
+				continue
+			}
+			mf.methods[method.Name] = f
 		}
 		smf[service.Name] = mf
 	}
@@ -155,13 +222,21 @@ func loadServerFilters(
 
 	smf := make(serviceMethodServerFilters, len(services))
 	for _, service := range services {
-		mf := make(methodServerFilters, len(service.Methods))
+		mf := &methodServerFilters{methods: make(map[string][]filter.ServerFilter, len(service.Methods))}
 		for _, method := range service.Methods {
 			f, err := loadMethodFilters(method.Filters)
 			if err != nil {
 				return nil, err
 			}
-			mf[method.Name] = f
+			route, isRoute, err := parseHTTPRoute(method.Name)
+			if err != nil {
+				return nil, err
+			}
+			if isRoute {
+				mf.routes = append(mf.routes, serverRouteFilters{route: route, filters: f})
+				continue
+			}
+			mf.methods[method.Name] = f
 		}
 		smf[service.Name] = mf
 	}
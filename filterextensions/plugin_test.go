@@ -16,6 +16,8 @@ package filterextensions_test
 import (
 	"bytes"
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,6 +25,7 @@ import (
 	"trpc.group/trpc-go/trpc-filter/filterextensions"
 	"trpc.group/trpc-go/trpc-go"
 	"trpc.group/trpc-go/trpc-go/filter"
+	thttp "trpc.group/trpc-go/trpc-go/http"
 	"trpc.group/trpc-go/trpc-go/plugin"
 )
 
@@ -105,6 +108,102 @@ func TestServiceMethodFilters_Setup(t *testing.T) {
 	testServerFilters(serverFilters, &smfCalled)
 }
 
+func TestServiceMethodFilters_HTTPRoute(t *testing.T) {
+	f := plugin.Get(filterextensions.PluginType, filterextensions.PluginName)
+	require.NotNil(t, f)
+
+	var called []string
+	for _, name := range []string{"get_user", "any_admin", "post_user"} {
+		name := name
+		filter.Register(name,
+			func(ctx context.Context, req interface{}, next filter.ServerHandleFunc) (interface{}, error) {
+				called = append(called, name)
+				return next(ctx, req)
+			},
+			func(ctx context.Context, req, rsp interface{}, next filter.ClientHandleFunc) error {
+				called = append(called, name)
+				return next(ctx, req, rsp)
+			})
+	}
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(yamlRouteCfg)))
+	require.Nil(t, f.Setup(filterextensions.PluginName, dec))
+
+	serverFilters := filter.GetServer(filterextensions.MethodFilters)
+	noopServerHandler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	for _, tt := range []struct {
+		method, target string
+		want           []string
+	}{
+		{http.MethodGet, "/v1/users/123", []string{"get_user"}},
+		{http.MethodGet, "/v1/users/123?verbose=1", []string{"get_user"}},
+		{http.MethodDelete, "/v1/users/123", nil},
+		{http.MethodGet, "/v1/users/123/friends", nil},
+		{http.MethodGet, "/v1/users", nil},
+		{http.MethodPut, "/admin/users/123/reset", []string{"any_admin"}},
+		{http.MethodPost, "/v1/users", []string{"post_user"}},
+	} {
+		called = nil
+		ctx := trpc.BackgroundContext()
+		msg := trpc.Message(ctx)
+		msg.WithCalleeServiceName("s_http")
+		msg.WithCalleeMethod("/trpc.app.server.Service/GetUser")
+		ctx = context.WithValue(ctx, thttp.ContextKeyHeader, &thttp.Header{
+			Request: httptest.NewRequest(tt.method, tt.target, nil),
+		})
+		_, err := serverFilters(ctx, nil, noopServerHandler)
+		require.Nil(t, err)
+		require.Equal(t, tt.want, called, "%s %s", tt.method, tt.target)
+	}
+
+	called = nil
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithCalleeServiceName("s_http")
+	msg.WithCalleeMethod("/trpc.app.server.Service/GetUser")
+	_, err := serverFilters(ctx, nil, noopServerHandler)
+	require.Nil(t, err)
+	require.Empty(t, called, "non-HTTP request never matches a route")
+
+	clientFilters := filter.GetClient(filterextensions.MethodFilters)
+	noopClientHandler := func(ctx context.Context, req, rsp interface{}) error { return nil }
+	called = nil
+	ctx = trpc.BackgroundContext()
+	msg = trpc.Message(ctx)
+	msg.WithCalleeServiceName("s_http")
+	msg.WithCalleeMethod("/v1/users")
+	msg.WithClientReqHead(&thttp.ClientReqHeader{})
+	require.Nil(t, clientFilters(ctx, nil, nil, noopClientHandler))
+	require.Equal(t, []string{"post_user"}, called, "thttp client defaults to POST")
+
+	called = nil
+	msg.WithCalleeMethod("/v1/users/123")
+	msg.WithClientReqHead(&thttp.ClientReqHeader{Method: http.MethodGet})
+	require.Nil(t, clientFilters(ctx, nil, nil, noopClientHandler))
+	require.Equal(t, []string{"get_user"}, called)
+
+	dec = yaml.NewDecoder(bytes.NewReader([]byte(`
+server:
+  - name: s_http
+    methods:
+      - name: GET /v1/{path=**}/users
+        filters: [get_user]
+`)))
+	require.NotNil(t, f.Setup(filterextensions.PluginName, dec), "wildcard must be the last segment")
+}
+
+const yamlRouteCfg = `
+server: &service
+  - name: s_http
+    methods:
+      - name: GET /v1/users/{id}
+        filters: [get_user]
+      - name: "* /admin/**"
+        filters: [any_admin]
+      - name: post /v1/users
+        filters: [post_user]
+client: *service
+`
+
 const yamlCfg = `
 server:
   - name: s_a
@@ -0,0 +1,130 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package filterextensions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+	thttp "trpc.group/trpc-go/trpc-go/http"
+)
+
+// anyHTTPMethod 匹配任意 HTTP 方法。
+const anyHTTPMethod = "*"
+
+var httpMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	anyHTTPMethod:      true,
+}
+
+// httpRoute 是形如 `GET /v1/users/{id}` 的 HTTP 路由。
+// 路径模板中 `{name}` 匹配一个路径段，`{name=**}` 或 `**` 只能出现在末尾，匹配剩余的所有路径段。
+type httpRoute struct {
+	method   string
+	segments []string
+	wildcard bool
+}
+
+// parseHTTPRoute 解析 method 配置的名字。如果名字不是 HTTP 路由，返回的 ok 为 false。
+func parseHTTPRoute(name string) (r *httpRoute, ok bool, err error) {
+	fields := strings.Fields(name)
+	if len(fields) != 2 || !httpMethods[strings.ToUpper(fields[0])] || !strings.HasPrefix(fields[1], "/") {
+		return nil, false, nil
+	}
+	r = &httpRoute{method: strings.ToUpper(fields[0])}
+	segments := splitPath(fields[1])
+	for i, seg := range segments {
+		if seg == "**" || (isPathVar(seg) && strings.HasSuffix(seg, "=**}")) {
+			if i != len(segments)-1 {
+				return nil, true, fmt.Errorf("route %q: %s must be the last segment", name, seg)
+			}
+			r.wildcard = true
+			break
+		}
+		r.segments = append(r.segments, seg)
+	}
+	return r, true, nil
+}
+
+// match 判断 HTTP 方法和路径是否匹配路由。
+func (r *httpRoute) match(method, path string) bool {
+	if r.method != anyHTTPMethod && r.method != method {
+		return false
+	}
+	segments := splitPath(path)
+	if len(segments) < len(r.segments) || (!r.wildcard && len(segments) != len(r.segments)) {
+		return false
+	}
+	for i, seg := range r.segments {
+		if seg == "*" || isPathVar(seg) {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if seg != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isPathVar(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+func splitPath(path string) []string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// serverHTTPRoute 返回服务端请求的 HTTP 方法和路径，非 HTTP 请求返回的 ok 为 false。
+// RESTful 服务使用 net/http 时同样会在 ctx 中设置 thttp.Header。
+func serverHTTPRoute(ctx context.Context) (method, path string, ok bool) {
+	req := thttp.Request(ctx)
+	if req == nil || req.URL == nil {
+		return "", "", false
+	}
+	return req.Method, req.URL.Path, true
+}
+
+// clientHTTPRoute 返回客户端请求的 HTTP 方法和路径，非 HTTP 请求返回的 ok 为 false。
+func clientHTTPRoute(msg codec.Msg) (method, path string, ok bool) {
+	head, isHTTP := msg.ClientReqHead().(*thttp.ClientReqHeader)
+	if !isHTTP || head == nil {
+		return "", "", false
+	}
+	method = head.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	path = msg.CalleeMethod()
+	if head.Request != nil && head.Request.URL != nil {
+		path = head.Request.URL.Path
+	}
+	return strings.ToUpper(method), path, true
+}
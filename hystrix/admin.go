@@ -0,0 +1,103 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"trpc.group/trpc-go/trpc-go/admin"
+)
+
+// AdminPattern is the admin path which shows the current circuit states.
+const AdminPattern = "/cmds/hystrix/circuits"
+
+// CircuitState is the state of a circuit.
+type CircuitState struct {
+	// Name is the command name, which is the RPC name, pattern, callee service or wildcard key of the matching config.
+	Name string `json:"name"`
+	// Node is the callee node address of per-node breakers.
+	Node string `json:"node,omitempty"`
+	// Breaker is BreakerHystrix or BreakerNative.
+	Breaker string `json:"breaker"`
+	// State is one of StateClosed, StateOpen, StateHalfOpen and StateUnknown.
+	// hystrix-go does not tell whether an open circuit is waiting for the sleep window or letting a probe through,
+	// so open circuits of hystrix-go are reported as StateUnknown.
+	State State `json:"state"`
+	// Requests and Failures are the calls and the failures in the sliding window of native breakers,
+	// they are not reported for hystrix-go.
+	Requests int64 `json:"requests,omitempty"`
+	Failures int64 `json:"failures,omitempty"`
+}
+
+// Circuits returns the states of the circuits created under the current config, sorted by name and node.
+// Circuits of the previous config are dropped once the config is reloaded.
+func Circuits() []CircuitState {
+	var states []CircuitState
+	currentRules().circuits.Range(func(k, v interface{}) bool {
+		name, c := k.(commandKey).cmd, v.(*command)
+		switch {
+		case c.nodes != nil:
+			c.nodes.mu.RLock()
+			for addr, b := range c.nodes.nodes {
+				states = append(states, nativeState(name, addr, b))
+			}
+			c.nodes.mu.RUnlock()
+		case c.breaker != nil:
+			states = append(states, nativeState(name, "", c.breaker))
+		default:
+			state := StateClosed
+			if cb, _, err := hystrix.GetCircuit(name); err == nil && cb.IsOpen() {
+				state = StateUnknown
+			}
+			states = append(states, CircuitState{Name: name, Breaker: BreakerHystrix, State: state})
+		}
+		return true
+	})
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Name != states[j].Name {
+			return states[i].Name < states[j].Name
+		}
+		return states[i].Node < states[j].Node
+	})
+	return states
+}
+
+func nativeState(name, node string, b *breaker) CircuitState {
+	state, requests, failures := b.stats()
+	return CircuitState{
+		Name:     name,
+		Node:     node,
+		Breaker:  BreakerNative,
+		State:    state,
+		Requests: requests,
+		Failures: failures,
+	}
+}
+
+func registerAdmin() {
+	admin.HandleFunc(AdminPattern, circuitsHandler)
+}
+
+func circuitsHandler(w http.ResponseWriter, _ *http.Request) {
+	b, err := json.Marshal(Circuits())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
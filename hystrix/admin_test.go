@@ -0,0 +1,68 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+)
+
+func TestCircuits(t *testing.T) {
+	resetState(t)
+	assert.Nil(t, Configure(&Config{
+		Breaker: BreakerNative,
+		Commands: map[string]hystrix.CommandConfig{
+			"/api/circuitsNative": {RequestVolumeThreshold: 1, SleepWindow: 1000},
+		},
+	}))
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName("/api/circuitsNative")
+	assert.NotNil(t, ClientFilter()(ctx, nil, nil, testPanicHandler))
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "/api/circuitsNative", "breaker": BreakerNative, "state": "open", "requests": 1.0, "failures": 1.0},
+	}, adminCircuits(t))
+
+	// The circuits of the previous config are dropped on reload.
+	assert.Nil(t, Configure(&Config{
+		Commands: map[string]hystrix.CommandConfig{
+			"/api/circuitsHystrix": {RequestVolumeThreshold: 1, ErrorPercentThreshold: 1, SleepWindow: 1000},
+		},
+	}))
+	ctx = trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName("/api/circuitsHystrix")
+	assert.Nil(t, ClientFilter()(ctx, nil, nil, testOKHandler))
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "/api/circuitsHystrix", "breaker": BreakerHystrix, "state": "closed"},
+	}, adminCircuits(t))
+
+	// hystrix-go does not tell open from half-open.
+	assert.Eventually(t, func() bool {
+		_ = ClientFilter()(ctx, nil, nil, testErrorHandler)
+		return Circuits()[0].State == StateUnknown
+	}, time.Second, time.Millisecond)
+}
+
+func adminCircuits(t *testing.T) []map[string]interface{} {
+	w := httptest.NewRecorder()
+	circuitsHandler(w, httptest.NewRequest("GET", AdminPattern, nil))
+	var states []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &states))
+	return states
+}
@@ -0,0 +1,440 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	metriccollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+const (
+	// BreakerHystrix runs commands with github.com/afex/hystrix-go.
+	BreakerHystrix = "hystrix"
+	// BreakerNative runs commands with the native circuit breaker of this package.
+	BreakerNative = "native"
+)
+
+// Defaults of native breaker, the others are the same as hystrix.
+const (
+	defaultWindow           = 10000
+	defaultBuckets          = 10
+	defaultHalfOpenRequests = 1
+)
+
+// NativeConfig is the extra config of native breaker. Timeout, maxconcurrentrequests, requestvolumethreshold,
+// sleepwindow and errorpercentthreshold still come from the command config.
+type NativeConfig struct {
+	// Window is the length of sliding window in milliseconds, default 10000.
+	Window int `yaml:"window"`
+	// Buckets is the number of buckets in the sliding window, default 10.
+	Buckets int `yaml:"buckets"`
+	// SlowCallDuration is the duration in milliseconds above which calls are considered slow, 0 disables it.
+	SlowCallDuration int `yaml:"slowcallduration"`
+	// SlowCallPercentThreshold opens the circuit once the percent of slow calls reaches it, default 100.
+	SlowCallPercentThreshold int `yaml:"slowcallpercentthreshold"`
+	// HalfOpenRequests is the number of probes allowed in half-open state, all of them must succeed
+	// to close the circuit, default 1.
+	HalfOpenRequests int `yaml:"halfopenrequests"`
+	// FailureCodes are the retcodes counted as failures. If empty, framework errors and errors without
+	// a trpc retcode are failures, while business errors are not.
+	FailureCodes []int `yaml:"failurecodes"`
+}
+
+// State is the state of a circuit.
+type State int32
+
+// States of circuit.
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+	// StateUnknown is reported when the breaker can not tell the state, see CircuitState.
+	StateUnknown
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// breakerSettings is the resolved settings of a native breaker.
+type breakerSettings struct {
+	timeout             time.Duration
+	maxConcurrent       int64
+	volumeThreshold     int64
+	sleepWindow         time.Duration
+	errorPercent        int64
+	window              time.Duration
+	bucketDuration      time.Duration
+	buckets             int
+	slowCallDuration    time.Duration
+	slowCallPercent     int64
+	halfOpenRequests    int64
+	failureCodes        map[int]bool
+	countUnknownFailure bool
+}
+
+func newBreakerSettings(cmd hystrix.CommandConfig, native NativeConfig) *breakerSettings {
+	orDefault := func(v, def int) int {
+		if v > 0 {
+			return v
+		}
+		return def
+	}
+	s := &breakerSettings{
+		timeout:          time.Duration(cmd.Timeout) * time.Millisecond,
+		maxConcurrent:    int64(orDefault(cmd.MaxConcurrentRequests, hystrix.DefaultMaxConcurrent)),
+		volumeThreshold:  int64(orDefault(cmd.RequestVolumeThreshold, hystrix.DefaultVolumeThreshold)),
+		sleepWindow:      time.Duration(orDefault(cmd.SleepWindow, hystrix.DefaultSleepWindow)) * time.Millisecond,
+		errorPercent:     int64(orDefault(cmd.ErrorPercentThreshold, hystrix.DefaultErrorPercentThreshold)),
+		window:           time.Duration(orDefault(native.Window, defaultWindow)) * time.Millisecond,
+		buckets:          orDefault(native.Buckets, defaultBuckets),
+		slowCallDuration: time.Duration(native.SlowCallDuration) * time.Millisecond,
+		slowCallPercent:  int64(orDefault(native.SlowCallPercentThreshold, 100)),
+		halfOpenRequests: int64(orDefault(native.HalfOpenRequests, defaultHalfOpenRequests)),
+		failureCodes:     make(map[int]bool, len(native.FailureCodes)),
+	}
+	s.bucketDuration = s.window / time.Duration(s.buckets)
+	if s.bucketDuration <= 0 {
+		s.bucketDuration = time.Millisecond
+	}
+	for _, code := range native.FailureCodes {
+		s.failureCodes[code] = true
+	}
+	s.countUnknownFailure = len(s.failureCodes) == 0
+	return s
+}
+
+// isFailure classifies the result of a call.
+func (s *breakerSettings) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		return s.countUnknownFailure
+	}
+	if len(s.failureCodes) > 0 {
+		return s.failureCodes[int(e.Code)]
+	}
+	return e.Type != errs.ErrorTypeBusiness
+}
+
+type bucket struct {
+	start    int64
+	total    int64
+	failures int64
+	slow     int64
+}
+
+// breaker is a circuit breaker with closed, open and half-open states.
+// It counts calls in a sliding window of buckets, and opens once the error ratio or slow call ratio
+// reaches the threshold.
+type breaker struct {
+	name       string
+	settings   atomic.Value // *breakerSettings
+	inflight   int64
+	collectors []metriccollector.MetricCollector
+
+	mu        sync.Mutex
+	state     State
+	openedAt  time.Time
+	probes    int64
+	successes int64
+	buckets   []bucket
+}
+
+// breakers maps command names to native breakers.
+var breakers sync.Map // map[string]*breaker
+
+// configureBreaker creates or updates the native breaker of a command.
+func configureBreaker(name string, cmd hystrix.CommandConfig, native NativeConfig) {
+	s := newBreakerSettings(cmd, native)
+	v, loaded := breakers.LoadOrStore(name, newBreaker(name, s))
+	if !loaded {
+		return
+	}
+	b := v.(*breaker)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.buckets) != s.buckets {
+		b.buckets = make([]bucket, s.buckets)
+	}
+	b.settings.Store(s)
+}
+
+func getBreaker(name string) *breaker {
+	v, ok := breakers.Load(name)
+	if !ok {
+		return nil
+	}
+	return v.(*breaker)
+}
+
+func newBreaker(name string, s *breakerSettings) *breaker {
+	b := &breaker{
+		name:       name,
+		buckets:    make([]bucket, s.buckets),
+		collectors: metriccollector.Registry.InitializeMetricCollectors(name),
+	}
+	b.settings.Store(s)
+	return b
+}
+
+func (b *breaker) loadSettings() *breakerSettings {
+	return b.settings.Load().(*breakerSettings)
+}
+
+// State returns the current state of the breaker.
+func (b *breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tryHalfOpen(time.Now())
+	return b.state
+}
+
+// stats returns the current state, the calls and the failures in the sliding window.
+func (b *breaker) stats() (state State, requests, failures int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tryHalfOpen(now)
+	requests, failures, _ = b.sum(b.loadSettings(), now)
+	return b.state, requests, failures
+}
+
+// allow reports whether a call is allowed. It returns hystrix.ErrCircuitOpen or hystrix.ErrMaxConcurrency
+// if the call is rejected, otherwise done must be called with the result.
+func (b *breaker) allow() (done func(err error, elapsed time.Duration), err error) {
+	s := b.loadSettings()
+	if atomic.AddInt64(&b.inflight, 1) > s.maxConcurrent {
+		atomic.AddInt64(&b.inflight, -1)
+		return nil, hystrix.ErrMaxConcurrency
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tryHalfOpen(now)
+	switch b.state {
+	case StateOpen:
+		b.mu.Unlock()
+		atomic.AddInt64(&b.inflight, -1)
+		return nil, hystrix.ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probes >= s.halfOpenRequests {
+			b.mu.Unlock()
+			atomic.AddInt64(&b.inflight, -1)
+			return nil, hystrix.ErrCircuitOpen
+		}
+		b.probes++
+	}
+	b.mu.Unlock()
+
+	return func(err error, elapsed time.Duration) {
+		atomic.AddInt64(&b.inflight, -1)
+		b.report(s, s.isFailure(err), s.slowCallDuration > 0 && elapsed >= s.slowCallDuration)
+	}, nil
+}
+
+// available reports whether the breaker would allow a call, without admitting it.
+func (b *breaker) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tryHalfOpen(time.Now())
+	return b.state == StateClosed || (b.state == StateHalfOpen && b.probes < b.loadSettings().halfOpenRequests)
+}
+
+// record reports a call which was not admitted by allow, a call in half-open state is taken as a probe.
+func (b *breaker) record(err error, elapsed time.Duration) {
+	s := b.loadSettings()
+	b.mu.Lock()
+	b.tryHalfOpen(time.Now())
+	if b.state == StateHalfOpen {
+		b.probes++
+	}
+	b.mu.Unlock()
+	b.report(s, s.isFailure(err), s.slowCallDuration > 0 && elapsed >= s.slowCallDuration)
+}
+
+func (b *breaker) report(s *breakerSettings, failure, slow bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case StateHalfOpen:
+		if failure || slow {
+			b.open(now)
+			return
+		}
+		if b.successes++; b.successes >= s.halfOpenRequests {
+			b.close()
+		}
+		return
+	case StateOpen:
+		// Calls started before the circuit opened.
+		return
+	}
+
+	bk := b.bucket(s, now)
+	bk.total++
+	if failure {
+		bk.failures++
+	}
+	if slow {
+		bk.slow++
+	}
+	total, failures, slows := b.sum(s, now)
+	if total < s.volumeThreshold {
+		return
+	}
+	if failures*100 >= s.errorPercent*total || (s.slowCallDuration > 0 && slows*100 >= s.slowCallPercent*total) {
+		b.open(now)
+	}
+}
+
+// bucket returns the bucket of now, resetting it if it is out of date.
+func (b *breaker) bucket(s *breakerSettings, now time.Time) *bucket {
+	start := now.UnixNano() / int64(s.bucketDuration) * int64(s.bucketDuration)
+	bk := &b.buckets[(start/int64(s.bucketDuration))%int64(len(b.buckets))]
+	if bk.start != start {
+		*bk = bucket{start: start}
+	}
+	return bk
+}
+
+func (b *breaker) sum(s *breakerSettings, now time.Time) (total, failures, slow int64) {
+	oldest := now.UnixNano() - int64(s.window)
+	for _, bk := range b.buckets {
+		if bk.start <= oldest {
+			continue
+		}
+		total += bk.total
+		failures += bk.failures
+		slow += bk.slow
+	}
+	return total, failures, slow
+}
+
+func (b *breaker) tryHalfOpen(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.loadSettings().sleepWindow {
+		b.state = StateHalfOpen
+		b.probes, b.successes = 0, 0
+	}
+}
+
+func (b *breaker) open(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+}
+
+func (b *breaker) close() {
+	b.state = StateClosed
+	for i := range b.buckets {
+		b.buckets[i] = bucket{}
+	}
+}
+
+// do runs fn in the calling goroutine under the protection of the breaker.
+// The timeout of the command is applied to the context passed to fn.
+// fallback, if not nil, is called with the error of rejection or failure, like hystrix.Do.
+func (b *breaker) do(ctx context.Context, fn func(context.Context) error, fallback func(error) error) error {
+	start := time.Now()
+	done, err := b.allow()
+	if err != nil {
+		result := metriccollector.MetricResult{Errors: 1, Rejects: 1}
+		if err == hystrix.ErrCircuitOpen {
+			result = metriccollector.MetricResult{Errors: 1, ShortCircuits: 1}
+		}
+		return b.finish(result, start, err, fallback)
+	}
+	s := b.loadSettings()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	runStart := time.Now()
+	err = fn(ctx)
+	elapsed := time.Since(runStart)
+	done(err, elapsed)
+	return b.finish(runResult(ctx, s, err, elapsed), start, err, fallback)
+}
+
+// runResult returns the metric result of a call that has been run.
+func runResult(ctx context.Context, s *breakerSettings, err error, elapsed time.Duration) metriccollector.MetricResult {
+	result := metriccollector.MetricResult{RunDuration: elapsed}
+	if s.isFailure(err) {
+		result.Errors, result.Failures = 1, 1
+	} else {
+		result.Successes = 1
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		result.Timeouts, result.ContextDeadlineExceeded = 1, 1
+	case context.Canceled:
+		result.ContextCanceled = 1
+	}
+	return result
+}
+
+// finish calls fallback on error and sends the metric result to the collectors of the breaker.
+func (b *breaker) finish(
+	result metriccollector.MetricResult,
+	start time.Time,
+	err error,
+	fallback func(error) error,
+) error {
+	if err != nil && fallback != nil {
+		if err = fallback(err); err == nil {
+			result.FallbackSuccesses = 1
+		} else {
+			result.FallbackFailures = 1
+		}
+	}
+	result.Attempts = 1
+	result.TotalDuration = time.Since(start)
+	result.ConcurrencyInUse = float64(atomic.LoadInt64(&b.inflight)) / float64(b.loadSettings().maxConcurrent)
+	for _, c := range b.collectors {
+		c.Update(result)
+	}
+	return err
+}
+
+func callFallback(err error, fallback func(error) error) error {
+	if err == nil || fallback == nil {
+		return err
+	}
+	return fallback(err)
+}
@@ -0,0 +1,170 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+func TestBreaker_ErrorRatio(t *testing.T) {
+	b := newBreaker("error_ratio", newBreakerSettings(hystrix.CommandConfig{
+		RequestVolumeThreshold: 4,
+		ErrorPercentThreshold:  50,
+		SleepWindow:            10,
+	}, NativeConfig{}))
+	ctx := context.Background()
+	frameErr := errs.NewFrameError(errs.RetClientNetErr, "network error")
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return frameErr }
+
+	assert.Nil(t, b.do(ctx, ok, nil))
+	assert.Equal(t, frameErr, b.do(ctx, fail, nil))
+	assert.Nil(t, b.do(ctx, ok, nil))
+	assert.Equal(t, StateClosed, b.State())
+	// Business errors are not failures.
+	assert.NotNil(t, b.do(ctx, func(context.Context) error { return errs.New(1000, "business") }, nil))
+	assert.Equal(t, StateClosed, b.State())
+	assert.Equal(t, frameErr, b.do(ctx, fail, nil))
+	assert.Equal(t, frameErr, b.do(ctx, fail, nil))
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, hystrix.ErrCircuitOpen, b.do(ctx, ok, nil))
+
+	// A failed probe opens the circuit again.
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.Equal(t, frameErr, b.do(ctx, fail, nil))
+	assert.Equal(t, StateOpen, b.State())
+
+	// A successful probe closes the circuit.
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, b.do(ctx, ok, nil))
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_HalfOpenProbes(t *testing.T) {
+	b := newBreaker("half_open", newBreakerSettings(hystrix.CommandConfig{
+		RequestVolumeThreshold: 1,
+		SleepWindow:            1,
+	}, NativeConfig{HalfOpenRequests: 2}))
+	ctx := context.Background()
+	assert.NotNil(t, b.do(ctx, func(context.Context) error { return errors.New("unknown") }, nil))
+	assert.Equal(t, StateOpen, b.State())
+	time.Sleep(time.Millisecond)
+
+	// Only two probes are allowed, both must succeed.
+	var secondErr, thirdErr error
+	assert.Nil(t, b.do(ctx, func(ctx context.Context) error {
+		secondErr = b.do(ctx, func(context.Context) error { return nil }, nil)
+		thirdErr = b.do(ctx, func(context.Context) error { return nil }, nil)
+		assert.Equal(t, StateHalfOpen, b.State())
+		return nil
+	}, nil))
+	assert.Nil(t, secondErr)
+	assert.Equal(t, hystrix.ErrCircuitOpen, thirdErr)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_SlowCall(t *testing.T) {
+	b := newBreaker("slow_call", newBreakerSettings(hystrix.CommandConfig{
+		RequestVolumeThreshold: 2,
+	}, NativeConfig{SlowCallDuration: 5, SlowCallPercentThreshold: 50}))
+	ctx := context.Background()
+	assert.Nil(t, b.do(ctx, func(context.Context) error { return nil }, nil))
+	assert.Nil(t, b.do(ctx, func(context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, nil))
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBreaker_FailureCodes(t *testing.T) {
+	s := newBreakerSettings(hystrix.CommandConfig{}, NativeConfig{FailureCodes: []int{1000}})
+	assert.False(t, s.isFailure(nil))
+	assert.True(t, s.isFailure(errs.New(1000, "business")))
+	assert.False(t, s.isFailure(errs.NewFrameError(errs.RetClientNetErr, "network error")))
+	assert.False(t, s.isFailure(errors.New("unknown")))
+
+	s = newBreakerSettings(hystrix.CommandConfig{}, NativeConfig{})
+	assert.False(t, s.isFailure(errs.New(1000, "business")))
+	assert.True(t, s.isFailure(errs.NewFrameError(errs.RetClientNetErr, "network error")))
+	assert.True(t, s.isFailure(errors.New("unknown")))
+}
+
+func TestBreaker_MaxConcurrencyAndTimeout(t *testing.T) {
+	b := newBreaker("max_concurrency", newBreakerSettings(hystrix.CommandConfig{
+		Timeout:               5,
+		MaxConcurrentRequests: 1,
+	}, NativeConfig{}))
+	var innerErr error
+	err := b.do(context.Background(), func(ctx context.Context) error {
+		innerErr = b.do(ctx, func(context.Context) error { return nil }, nil)
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(err error) error { return errs.Wrap(err, errs.RetClientTimeout, "fallback") })
+	assert.Equal(t, hystrix.ErrMaxConcurrency, innerErr)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, errs.RetClientTimeout, errs.Code(err))
+}
+
+func TestBreaker_Sliding(t *testing.T) {
+	b := newBreaker("sliding", newBreakerSettings(hystrix.CommandConfig{
+		RequestVolumeThreshold: 2,
+	}, NativeConfig{Window: 20, Buckets: 2}))
+	ctx := context.Background()
+	assert.NotNil(t, b.do(ctx, func(context.Context) error { return errors.New("unknown") }, nil))
+	time.Sleep(25 * time.Millisecond)
+	// The first failure has slid out of the window.
+	assert.Nil(t, b.do(ctx, func(context.Context) error { return nil }, nil))
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestNativeFilter(t *testing.T) {
+	resetState(t)
+	assert.NotNil(t, Configure(&Config{Breaker: "unknown"}))
+	assert.Nil(t, Configure(&Config{
+		Breaker: BreakerNative,
+		Commands: map[string]hystrix.CommandConfig{
+			"/api/nativeClient*": {RequestVolumeThreshold: 1, SleepWindow: 1000},
+			"/api/nativeServer*": {RequestVolumeThreshold: 1, SleepWindow: 1000},
+		},
+	}))
+
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName("/api/nativeClient")
+	f := ClientFilter()
+	assert.Nil(t, f(ctx, nil, nil, testOKHandler))
+	assert.NotNil(t, f(ctx, nil, nil, testPanicHandler))
+	assertCircuitError(t, f(ctx, nil, nil, testOKHandler), hystrix.ErrCircuitOpen)
+	assert.Equal(t, StateOpen, getBreaker("/api/nativeClient*").State())
+
+	ctx = trpc.BackgroundContext()
+	trpc.Message(ctx).WithServerRPCName("/api/nativeServer")
+	sf := ServerFilter()
+	_, err := sf(ctx, nil, testOKServerHandler)
+	assert.Nil(t, err)
+	_, err = sf(ctx, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errs.NewFrameError(errs.RetServerSystemErr, "system error")
+	})
+	assert.NotNil(t, err)
+	_, err = sf(ctx, nil, testOKServerHandler)
+	assertCircuitError(t, err, hystrix.ErrCircuitOpen)
+}
@@ -0,0 +1,290 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/afex/hystrix-go/hystrix"
+)
+
+// Config is the configuration of hystrix plugin.
+type Config struct {
+	// Services sets the default command config of each callee service.
+	// It is used when no RPC name or pattern in Commands matches.
+	Services map[string]hystrix.CommandConfig `yaml:"services"`
+	// Fallbacks sets the declarative fallback of each RPC name.
+	Fallbacks map[string]*FallbackConfig `yaml:"fallbacks"`
+	// Breaker selects the circuit breaker implementation, BreakerHystrix (default) or BreakerNative.
+	Breaker string `yaml:"breaker"`
+	// Native is the extra config of native breaker.
+	Native NativeConfig `yaml:"native"`
+	// PerNode keys the breakers of client calls by callee node address, with the native breaker.
+	// Nodes with open circuits are skipped by the selector instead of failing the call.
+	PerNode bool `yaml:"pernode"`
+	// Watch reloads the other fields from a watched config.
+	Watch *WatchConfig `yaml:"watch"`
+	// Commands maps RPC names to command configs. Besides an exact RPC name, the key can be
+	// a glob pattern like "/trpc.app.user.*", the WildcardKey, or an exclusion prefixed by ExcludeKey.
+	Commands map[string]hystrix.CommandConfig `yaml:",inline"`
+}
+
+// WatchConfig describes where to load and watch the command configs.
+type WatchConfig struct {
+	// Provider is the name of trpc config provider, default "file".
+	Provider string `yaml:"provider"`
+	// Path is the config path passed to the provider.
+	Path string `yaml:"path"`
+	// Codec is the name of trpc config codec, default "yaml".
+	Codec string `yaml:"codec"`
+}
+
+// rules is the current *ruleSet.
+var rules atomic.Value
+
+func init() {
+	rules.Store(newRuleSet(&Config{}))
+}
+
+// Configure replaces the current command configs with cfg.
+// It can be called at any time, the following requests will match against the new configs.
+func Configure(cfg *Config) error {
+	if cfg.Breaker != "" && cfg.Breaker != BreakerHystrix && cfg.Breaker != BreakerNative {
+		return fmt.Errorf("hystrix: unknown breaker %q", cfg.Breaker)
+	}
+	for rpcName, fallback := range cfg.Fallbacks {
+		if err := fallback.init(); err != nil {
+			return fmt.Errorf("%w, rpc name: %s", err, rpcName)
+		}
+	}
+	rules.Store(newRuleSet(cfg))
+	return nil
+}
+
+func currentRules() *ruleSet {
+	return rules.Load().(*ruleSet)
+}
+
+// ruleSet resolves RPC names to hystrix commands.
+type ruleSet struct {
+	commands  map[string]hystrix.CommandConfig
+	services  map[string]hystrix.CommandConfig
+	fallbacks map[string]*FallbackConfig
+	patterns  []pattern
+	excludes  []string
+	wildcard  *hystrix.CommandConfig
+	native    *NativeConfig
+	perNode   bool
+
+	wildcardKey string
+	commandSet  sync.Map // map[commandKey]*command
+	circuits    sync.Map // map[commandKey]*command, the configured commands shown by Circuits.
+	cache       sync.Map // map[cacheKey]*resolution
+	cached      int64
+}
+
+// maxCachedRPCs bounds the number of cached matching results, RPC names beyond it,
+// such as RESTful paths with IDs, are matched on each request.
+const maxCachedRPCs = 10000
+
+type pattern struct {
+	glob string
+	cfg  hystrix.CommandConfig
+}
+
+type cacheKey struct {
+	service string
+	rpc     string
+	client  bool
+}
+
+type commandKey struct {
+	cmd   string
+	nodes bool
+}
+
+// command is the circuit of a command, which is shared by the RPCs matching the same config key.
+type command struct {
+	breaker *breaker      // nil if the command runs with hystrix.
+	nodes   *nodeBreakers // not nil if the breakers are keyed by node.
+	once    sync.Once
+}
+
+// resolution is the matching result of an RPC name.
+type resolution struct {
+	rpc      string
+	cmd      string
+	cfg      hystrix.CommandConfig
+	ok       bool
+	fallback *FallbackConfig
+	*command
+	once sync.Once
+}
+
+// do runs fn under the protection of the breaker of the command.
+func (r *resolution) do(ctx context.Context, fn func(context.Context) error, fallback func(error) error) error {
+	if r.nodes != nil {
+		return r.nodes.do(ctx, fn, fallback)
+	}
+	if r.breaker != nil {
+		return r.breaker.do(ctx, fn, fallback)
+	}
+	return hystrix.Do(r.cmd, func() error { return fn(ctx) }, fallback)
+}
+
+func newRuleSet(cfg *Config) *ruleSet {
+	rs := &ruleSet{
+		commands:  make(map[string]hystrix.CommandConfig),
+		services:  cfg.Services,
+		fallbacks: cfg.Fallbacks,
+		perNode:   cfg.PerNode,
+
+		wildcardKey: WildcardKey,
+	}
+	if cfg.Breaker == BreakerNative {
+		native := cfg.Native
+		rs.native = &native
+	}
+	for key, c := range cfg.Commands {
+		switch {
+		case key == WildcardKey:
+			c := c
+			rs.wildcard = &c
+		case strings.HasPrefix(key, ExcludeKey):
+			rs.excludes = append(rs.excludes, strings.TrimPrefix(key, ExcludeKey))
+		case isGlob(key):
+			rs.patterns = append(rs.patterns, pattern{glob: key, cfg: c})
+		default:
+			rs.commands[key] = c
+		}
+	}
+	// The most specific pattern, the one with more literal characters, takes precedence.
+	sort.Slice(rs.patterns, func(i, j int) bool {
+		li, lj := literalLen(rs.patterns[i].glob), literalLen(rs.patterns[j].glob)
+		if li != lj {
+			return li > lj
+		}
+		return rs.patterns[i].glob < rs.patterns[j].glob
+	})
+	return rs
+}
+
+// resolve returns the matching result of an RPC, the RPC is not protected if ok of the result is false.
+// The priority is: exact RPC name > exclusion > glob pattern > callee service default > wildcard.
+// The command is named after the matching key, so the RPCs matching the same pattern, service default
+// or wildcard share one circuit, like the wildcard command of the previous versions.
+func (rs *ruleSet) resolve(service, rpc string, client bool) *resolution {
+	key := cacheKey{service: service, rpc: rpc, client: client}
+	r := &resolution{rpc: rpc}
+	if v, ok := rs.cache.Load(key); ok {
+		r = v.(*resolution)
+	} else if atomic.LoadInt64(&rs.cached) < maxCachedRPCs {
+		v, loaded := rs.cache.LoadOrStore(key, r)
+		if !loaded {
+			atomic.AddInt64(&rs.cached, 1)
+		}
+		r = v.(*resolution)
+	}
+	r.once.Do(func() { rs.init(r, service, client) })
+	return r
+}
+
+func (rs *ruleSet) init(r *resolution, service string, client bool) {
+	r.cmd, r.cfg, r.ok = rs.match(service, r.rpc)
+	if !r.ok {
+		return
+	}
+	r.fallback = rs.fallbacks[r.rpc]
+	nodes := client && rs.perNode
+	v, _ := rs.commandSet.LoadOrStore(commandKey{cmd: r.cmd, nodes: nodes}, &command{})
+	r.command = v.(*command)
+	r.command.once.Do(func() {
+		switch {
+		case nodes:
+			native := NativeConfig{}
+			if rs.native != nil {
+				native = *rs.native
+			}
+			r.nodes = configureNodeBreakers(r.cmd, r.cfg, native)
+		case rs.native != nil:
+			configureBreaker(r.cmd, r.cfg, *rs.native)
+			r.breaker = getBreaker(r.cmd)
+		default:
+			hystrix.ConfigureCommand(r.cmd, r.cfg)
+		}
+		rs.circuits.Store(commandKey{cmd: r.cmd, nodes: nodes}, r.command)
+	})
+}
+
+// match returns the command name and config of the RPC.
+func (rs *ruleSet) match(service, rpc string) (string, hystrix.CommandConfig, bool) {
+	if c, ok := rs.commands[rpc]; ok {
+		return rpc, c, true
+	}
+	for _, exclude := range rs.excludes {
+		if globMatch(exclude, rpc) {
+			return "", hystrix.CommandConfig{}, false
+		}
+	}
+	for _, p := range rs.patterns {
+		if globMatch(p.glob, rpc) {
+			return p.glob, p.cfg, true
+		}
+	}
+	if c, ok := rs.services[service]; ok {
+		return service, c, true
+	}
+	if rs.wildcard != nil {
+		return rs.wildcardKey, *rs.wildcard, true
+	}
+	return "", hystrix.CommandConfig{}, false
+}
+
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?")
+}
+
+func literalLen(s string) int {
+	return len(s) - strings.Count(s, "*") - strings.Count(s, "?")
+}
+
+// globMatch reports whether s matches the glob pattern,
+// in which '*' matches any sequence of characters including '/', and '?' matches any single character.
+func globMatch(pattern, s string) bool {
+	var p, i, starP, starI = 0, 0, -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starI = p, i
+			p++
+		case starP >= 0:
+			starI++
+			p, i = starP+1, starI
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
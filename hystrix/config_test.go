@@ -0,0 +1,157 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"testing"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"trpc.group/trpc-go/trpc-go/config"
+)
+
+const rulesInfo = `
+services:
+  trpc.app.order.Order:
+    timeout: 300
+watch:
+  provider: hystrix_test
+  path: hystrix.yaml
+/trpc.app.user.User/Get:
+  timeout: 100
+/trpc.app.user.*:
+  timeout: 200
+/trpc.app.user.User/*:
+  timeout: 250
+"*":
+  timeout: 400
+_/trpc.app.user.User/Ping:
+_/trpc.app.order.*/Health:
+`
+
+func TestConfig_Decode(t *testing.T) {
+	cfg := &Config{}
+	assert.Nil(t, yaml.Unmarshal([]byte(rulesInfo), cfg))
+	assert.Equal(t, 300, cfg.Services["trpc.app.order.Order"].Timeout)
+	assert.Equal(t, &WatchConfig{Provider: "hystrix_test", Path: "hystrix.yaml"}, cfg.Watch)
+	assert.Len(t, cfg.Commands, 6)
+	assert.Equal(t, 100, cfg.Commands["/trpc.app.user.User/Get"].Timeout)
+	assert.Contains(t, cfg.Commands, "_/trpc.app.user.User/Ping")
+}
+
+func TestRuleSet_Resolve(t *testing.T) {
+	cfg := &Config{}
+	assert.Nil(t, yaml.Unmarshal([]byte(rulesInfo), cfg))
+	rs := newRuleSet(cfg)
+
+	for _, tt := range []struct {
+		service, rpc string
+		ok           bool
+		cmd          string
+		timeout      int64
+	}{
+		{"trpc.app.user.User", "/trpc.app.user.User/Get", true, "/trpc.app.user.User/Get", 100},
+		{"trpc.app.user.User", "/trpc.app.user.User/Set", true, "/trpc.app.user.User/*", 250},
+		{"trpc.app.user.Admin", "/trpc.app.user.Admin/Set", true, "/trpc.app.user.*", 200},
+		{"trpc.app.user.User", "/trpc.app.user.User/Ping", false, "", 0},
+		{"trpc.app.order.Order", "/trpc.app.order.Order/Create", true, "trpc.app.order.Order", 300},
+		{"trpc.app.order.Order", "/trpc.app.order.Order/Health", false, "", 0},
+		{"trpc.app.other.Other", "/trpc.app.other.Other/Do", true, "*", 400},
+	} {
+		r := rs.resolve(tt.service, tt.rpc, false)
+		assert.Equal(t, tt.ok, r.ok, tt.rpc)
+		if !r.ok {
+			continue
+		}
+		assert.Equal(t, tt.cmd, r.cmd, tt.rpc)
+		assert.Equal(t, tt.timeout, hystrix.GetCircuitSettings()[r.cmd].Timeout.Milliseconds(), tt.rpc)
+	}
+
+	// The matching result is cached per RPC name.
+	v, ok := rs.cache.Load(cacheKey{service: "trpc.app.user.User", rpc: "/trpc.app.user.User/Set"})
+	assert.True(t, ok)
+	assert.Equal(t, "/trpc.app.user.User/*", v.(*resolution).cmd)
+
+	// The RPCs matching the same key share one circuit.
+	r := rs.resolve("trpc.app.user.User", "/trpc.app.user.User/Del", false)
+	assert.Equal(t, "/trpc.app.user.User/Del", r.rpc)
+	assert.Same(t, v.(*resolution).command, r.command)
+
+	// RPC names beyond the cache limit are still matched, but not cached.
+	rs.cached = maxCachedRPCs
+	r = rs.resolve("trpc.app.other.Other", "/api/users/10086", false)
+	assert.True(t, r.ok)
+	assert.Equal(t, "*", r.cmd)
+	_, ok = rs.cache.Load(cacheKey{service: "trpc.app.other.Other", rpc: "/api/users/10086"})
+	assert.False(t, ok)
+
+	// No wildcard, nothing matches.
+	assert.False(t, newRuleSet(&Config{}).resolve("trpc.app.user.User", "/trpc.app.user.User/Get", false).ok)
+}
+
+func TestGlobMatch(t *testing.T) {
+	assert.True(t, globMatch("/trpc.app.user.*", "/trpc.app.user.User/Get"))
+	assert.True(t, globMatch("*", ""))
+	assert.True(t, globMatch("/a/?et", "/a/Get"))
+	assert.True(t, globMatch("/a*/b*c", "/a/x/b/yc"))
+	assert.False(t, globMatch("/a*/b*c", "/a/x/b/yd"))
+	assert.False(t, globMatch("/trpc.app.user.*", "/trpc.app.order.Order/Get"))
+	assert.False(t, globMatch("/a/?et", "/a/et"))
+}
+
+type testProvider struct {
+	data map[string][]byte
+	cb   config.ProviderCallback
+}
+
+func (p *testProvider) Name() string                     { return "hystrix_test" }
+func (p *testProvider) Read(path string) ([]byte, error) { return p.data[path], nil }
+func (p *testProvider) Watch(cb config.ProviderCallback) { p.cb = cb }
+
+func TestWatch(t *testing.T) {
+	resetState(t)
+	p := &testProvider{data: map[string][]byte{"hystrix.yaml": []byte(`
+/api/watched:
+  timeout: 10
+`)}}
+	config.RegisterProvider(p)
+
+	base := &Config{
+		Watch:    &WatchConfig{Provider: p.Name(), Path: "hystrix.yaml"},
+		Commands: map[string]hystrix.CommandConfig{"/api/base": {Timeout: 20}},
+	}
+	assert.Nil(t, watch(base))
+	assert.True(t, currentRules().resolve("", "/api/base", false).ok)
+	assert.True(t, currentRules().resolve("", "/api/watched", false).ok)
+	assert.Equal(t, int64(10), hystrix.GetCircuitSettings()["/api/watched"].Timeout.Milliseconds())
+
+	// Reload on change.
+	p.cb("hystrix.yaml", []byte(`
+/api/watched:
+  timeout: 30
+"/api/new*":
+`))
+	assert.True(t, currentRules().resolve("", "/api/newOne", false).ok)
+	assert.True(t, currentRules().resolve("", "/api/watched", false).ok)
+	assert.Equal(t, int64(30), hystrix.GetCircuitSettings()["/api/watched"].Timeout.Milliseconds())
+
+	// Changes of other paths and invalid configs are ignored.
+	p.cb("other.yaml", []byte(`{}`))
+	p.cb("hystrix.yaml", []byte(`[invalid`))
+	assert.True(t, currentRules().resolve("", "/api/newOne", false).ok)
+
+	assert.NotNil(t, watch(&Config{Watch: &WatchConfig{Provider: "not_exist"}}))
+	assert.NotNil(t, watch(&Config{Watch: &WatchConfig{Provider: p.Name(), Codec: "not_exist"}}))
+}
@@ -0,0 +1,253 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+const (
+	// FallbackStatic returns a static response body from config.
+	FallbackStatic = "static"
+	// FallbackCache returns the last successful response of the same RPC.
+	FallbackCache = "cache"
+)
+
+// ServerFallbackFunc is called when a server RPC protected by hystrix fails.
+// err is the failure, circuit errors are already converted to trpc errors.
+type ServerFallbackFunc func(ctx context.Context, req interface{}, err error) (interface{}, error)
+
+// ClientFallbackFunc is called when a client RPC protected by hystrix fails.
+// It may fill rsp and return nil to hide the failure from the caller.
+// On hystrix.ErrTimeout of hystrix-go, the timed out call may still be writing rsp in its own goroutine.
+type ClientFallbackFunc func(ctx context.Context, req, rsp interface{}, err error) error
+
+// FallbackConfig is the declarative fallback of an RPC.
+// It is only triggered by circuit errors: circuit open, max concurrency and timeout.
+// Client fallbacks are not triggered by timeout, since rsp is still used by the timed out call.
+// Server fallbacks need the response type, which is learned from a successful response or RegisterResponseType.
+type FallbackConfig struct {
+	// Type is FallbackStatic or FallbackCache.
+	Type string `yaml:"type"`
+	// Body is the static response body, encoded in the serialization of the method.
+	Body string `yaml:"body"`
+	// Base64 indicates that Body is base64 encoded, used for binary serializations like pb.
+	Base64 bool `yaml:"base64"`
+
+	data []byte
+}
+
+func (c *FallbackConfig) init() error {
+	switch c.Type {
+	case FallbackStatic:
+		if !c.Base64 {
+			c.data = []byte(c.Body)
+			return nil
+		}
+		data, err := base64.StdEncoding.DecodeString(c.Body)
+		if err != nil {
+			return fmt.Errorf("hystrix: decode base64 fallback body err: %w", err)
+		}
+		c.data = data
+		return nil
+	case FallbackCache:
+		return nil
+	default:
+		return fmt.Errorf("hystrix: unknown fallback type %q", c.Type)
+	}
+}
+
+var fallbacks = struct {
+	sync.RWMutex
+	server map[string]ServerFallbackFunc
+	client map[string]ClientFallbackFunc
+}{
+	server: make(map[string]ServerFallbackFunc),
+	client: make(map[string]ClientFallbackFunc),
+}
+
+// RegisterServerFallback registers the fallback of a server RPC, which takes precedence over the one in config.
+// Unlike the declarative fallback, it is called on every failure, including errors returned by the handler.
+func RegisterServerFallback(rpcName string, fn ServerFallbackFunc) {
+	fallbacks.Lock()
+	defer fallbacks.Unlock()
+	fallbacks.server[rpcName] = fn
+}
+
+// RegisterClientFallback registers the fallback of a client RPC, which takes precedence over the one in config.
+// Unlike the declarative fallback, it is called on every failure, including errors returned by the callee.
+func RegisterClientFallback(rpcName string, fn ClientFallbackFunc) {
+	fallbacks.Lock()
+	defer fallbacks.Unlock()
+	fallbacks.client[rpcName] = fn
+}
+
+func serverFallback(rpcName string, cfg *FallbackConfig) ServerFallbackFunc {
+	fallbacks.RLock()
+	fn, ok := fallbacks.server[rpcName]
+	fallbacks.RUnlock()
+	if ok {
+		return fn
+	}
+	if cfg == nil {
+		return nil
+	}
+	return func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+		if !isCircuitError(err) {
+			return nil, err
+		}
+		typ, ok := responseTypes.Load(rpcName)
+		if !ok {
+			// The response type is learned from successful responses, nothing to decode into yet.
+			return nil, err
+		}
+		rsp := reflect.New(typ.(reflect.Type).Elem()).Interface()
+		if decodeErr := decodeFallback(ctx, rpcName, cfg, rsp); decodeErr != nil {
+			return nil, err
+		}
+		return rsp, nil
+	}
+}
+
+func clientFallback(rpcName string, cfg *FallbackConfig) ClientFallbackFunc {
+	fallbacks.RLock()
+	fn, ok := fallbacks.client[rpcName]
+	fallbacks.RUnlock()
+	if ok {
+		return fn
+	}
+	if cfg == nil {
+		return nil
+	}
+	return func(ctx context.Context, req, rsp interface{}, err error) error {
+		// The timed out call of hystrix-go is still running in another goroutine and may write rsp,
+		// which must not be written concurrently.
+		if !isCircuitError(err) || errors.Is(err, hystrix.ErrTimeout) {
+			return err
+		}
+		if decodeErr := decodeFallback(ctx, rpcName, cfg, rsp); decodeErr != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// responses caches the last successful response body of RPCs with cache fallback.
+var responses sync.Map // map[string]cachedResponse
+
+// responseTypes records the server response type of RPCs with declarative fallback.
+var responseTypes sync.Map // map[string]reflect.Type
+
+// RegisterResponseType registers the response type of a server RPC by a response of it, such as &pb.GetProfileRsp{},
+// so that its declarative fallback takes effect before the first successful response.
+func RegisterResponseType(rpcName string, rsp interface{}) {
+	if typ := reflect.TypeOf(rsp); typ != nil && typ.Kind() == reflect.Ptr {
+		responseTypes.Store(rpcName, typ)
+	}
+}
+
+type cachedResponse struct {
+	serializationType int
+	data              []byte
+}
+
+// storeResponse records the successful response of an RPC for its declarative fallback.
+func storeResponse(ctx context.Context, rpcName string, cfg *FallbackConfig, rsp interface{}, server bool) {
+	if cfg == nil || rsp == nil {
+		return
+	}
+	if server {
+		RegisterResponseType(rpcName, rsp)
+	}
+	if cfg.Type != FallbackCache {
+		return
+	}
+	serializationType := codec.Message(ctx).SerializationType()
+	data, err := codec.Marshal(serializationType, rsp)
+	if err != nil {
+		return
+	}
+	responses.Store(rpcName, cachedResponse{serializationType: serializationType, data: data})
+}
+
+// runGuard stops a timed out run from recording its response after the filter has returned or fallen back.
+type runGuard struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (g *runGuard) do(f func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		f()
+	}
+}
+
+func (g *runGuard) close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+}
+
+func decodeFallback(ctx context.Context, rpcName string, cfg *FallbackConfig, rsp interface{}) error {
+	serializationType, data := codec.Message(ctx).SerializationType(), cfg.data
+	if cfg.Type == FallbackCache {
+		v, ok := responses.Load(rpcName)
+		if !ok {
+			return errors.New("no cached response")
+		}
+		cached := v.(cachedResponse)
+		serializationType, data = cached.serializationType, cached.data
+	}
+	return codec.Unmarshal(serializationType, data, rsp)
+}
+
+func isCircuitError(err error) bool {
+	var circuitErr hystrix.CircuitError
+	return errors.As(err, &circuitErr)
+}
+
+// serverError converts hystrix circuit errors to trpc server errors.
+func serverError(err error) error {
+	switch err {
+	case hystrix.ErrCircuitOpen, hystrix.ErrMaxConcurrency:
+		return errs.WrapFrameError(err, errs.RetServerOverload, "hystrix filter")
+	case hystrix.ErrTimeout:
+		return errs.WrapFrameError(err, errs.RetServerTimeout, "hystrix filter")
+	default:
+		return err
+	}
+}
+
+// clientError converts hystrix circuit errors to trpc client errors.
+func clientError(err error) error {
+	switch err {
+	case hystrix.ErrCircuitOpen, hystrix.ErrMaxConcurrency:
+		return errs.WrapFrameError(err, errs.RetClientOverload, "hystrix filter")
+	case hystrix.ErrTimeout:
+		return errs.WrapFrameError(err, errs.RetClientTimeout, "hystrix filter")
+	default:
+		return err
+	}
+}
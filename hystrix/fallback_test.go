@@ -0,0 +1,194 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/filter"
+)
+
+const fallbackInfo = `
+fallbacks:
+  /api/fallbackStatic:
+    type: static
+    body: '{"name":"static"}'
+  /api/fallbackBase64:
+    type: static
+    body: eyJuYW1lIjoiYmFzZTY0In0=
+    base64: true
+  /api/fallbackCache:
+    type: cache
+  /api/fallbackServer:
+    type: static
+    body: '{"name":"server"}'
+  /api/fallbackServerType:
+    type: static
+    body: '{"name":"registered"}'
+  /api/fallbackTimeout:
+    type: static
+    body: '{"name":"timeout"}'
+"/api/fallback*":
+  timeout: 1000
+  maxconcurrentrequests: 1
+/api/fallbackServer:
+  timeout: 10
+/api/fallbackServerType:
+  timeout: 10
+/api/fallbackTimeout:
+  timeout: 10
+`
+
+type testRsp struct {
+	Name string `json:"name"`
+}
+
+func newFallbackContext(rpcName string) context.Context {
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithClientRPCName(rpcName)
+	msg.WithServerRPCName(rpcName)
+	msg.WithSerializationType(codec.SerializationTypeJSON)
+	return ctx
+}
+
+// occupy runs a call of the client filter which blocks until release is called,
+// so that the following calls of the same circuit are rejected by max concurrency.
+func occupy(t *testing.T, f filter.ClientFilter, ctx context.Context) (release func()) {
+	started, done, finished := make(chan struct{}), make(chan struct{}), make(chan error, 1)
+	handler := func(ctx context.Context, req, rsp interface{}) error {
+		close(started)
+		<-done
+		return nil
+	}
+	deadline := time.After(time.Second)
+	for {
+		go func() { finished <- f(ctx, nil, &testRsp{}, handler) }()
+		select {
+		case <-started:
+			return func() {
+				close(done)
+				select {
+				case <-finished:
+				case <-time.After(time.Second):
+					t.Error("the occupying call is not finished")
+				}
+			}
+		case <-finished:
+			// hystrix-go returns the ticket of the previous call after it returns, retry until it is returned.
+			time.Sleep(time.Millisecond)
+		case <-deadline:
+			t.Fatal("the occupying call is not started")
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	resetState(t)
+	cfg := &Config{}
+	assert.Nil(t, yaml.Unmarshal([]byte(fallbackInfo), cfg))
+	assert.Nil(t, Configure(cfg))
+	f := ClientFilter()
+
+	// Handler errors are not hidden by declarative fallbacks.
+	assert.EqualError(t, f(newFallbackContext("/api/fallbackStatic"), nil, &testRsp{}, testErrorHandler), "rpc error")
+
+	// Last successful response.
+	// The call may be rejected until the ticket of the previous call is returned.
+	ctx := newFallbackContext("/api/fallbackCache")
+	assert.Eventually(t, func() bool {
+		return f(ctx, nil, &testRsp{}, func(ctx context.Context, req, rsp interface{}) error {
+			rsp.(*testRsp).Name = "cached"
+			return nil
+		}) == nil
+	}, time.Second, time.Millisecond)
+
+	// The calls are rejected while the only ticket of "/api/fallback*" is occupied.
+	release := occupy(t, f, newFallbackContext("/api/fallbackOccupy"))
+	rsp := &testRsp{}
+	assert.Nil(t, f(newFallbackContext("/api/fallbackStatic"), nil, rsp, testOKHandler))
+	assert.Equal(t, "static", rsp.Name)
+	rsp = &testRsp{}
+	assert.Nil(t, f(newFallbackContext("/api/fallbackBase64"), nil, rsp, testOKHandler))
+	assert.Equal(t, "base64", rsp.Name)
+	rsp = &testRsp{}
+	assert.Nil(t, f(ctx, nil, rsp, testOKHandler))
+	assert.Equal(t, "cached", rsp.Name)
+	release()
+
+	// Client fallbacks are not triggered by timeout, the timed out call may still write rsp.
+	rsp = &testRsp{}
+	err := f(newFallbackContext("/api/fallbackTimeout"), nil, rsp, testTimeoutHandler)
+	assertCircuitError(t, err, hystrix.ErrTimeout)
+	assert.Empty(t, rsp.Name)
+
+	// Server response type is learned from successful responses.
+	sf := ServerFilter()
+	ctx = newFallbackContext("/api/fallbackServer")
+	_, err = sf(ctx, nil, testTimeoutServerHandler)
+	assertCircuitError(t, err, hystrix.ErrTimeout)
+	serverRsp, err := sf(ctx, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &testRsp{Name: "handler"}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &testRsp{Name: "handler"}, serverRsp)
+	serverRsp, err = sf(ctx, nil, testTimeoutServerHandler)
+	assert.Nil(t, err)
+	assert.Equal(t, &testRsp{Name: "server"}, serverRsp)
+
+	// Or registered before the first request.
+	RegisterResponseType("/api/fallbackServerType", &testRsp{})
+	serverRsp, err = sf(newFallbackContext("/api/fallbackServerType"), nil, testTimeoutServerHandler)
+	assert.Nil(t, err)
+	assert.Equal(t, &testRsp{Name: "registered"}, serverRsp)
+}
+
+func TestRegisterFallback(t *testing.T) {
+	resetState(t)
+	assert.Nil(t, Configure(&Config{Commands: map[string]hystrix.CommandConfig{"/api/registered": {}}}))
+
+	var fallbackErr error
+	RegisterClientFallback("/api/registered", func(ctx context.Context, req, rsp interface{}, err error) error {
+		fallbackErr = err
+		rsp.(*testRsp).Name = "client"
+		return nil
+	})
+	rsp := &testRsp{}
+	assert.Nil(t, ClientFilter()(newFallbackContext("/api/registered"), nil, rsp, testErrorHandler))
+	assert.EqualError(t, fallbackErr, "rpc error")
+	assert.Equal(t, "client", rsp.Name)
+
+	RegisterServerFallback("/api/registered", func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+		return nil, errs.New(errs.RetServerSystemErr, "server fallback")
+	})
+	_, err := ServerFilter()(newFallbackContext("/api/registered"), nil, testErrorServerHandler)
+	assert.Equal(t, "server fallback", errs.Msg(err))
+}
+
+func TestConfigure_InvalidFallback(t *testing.T) {
+	assert.NotNil(t, Configure(&Config{Fallbacks: map[string]*FallbackConfig{"/api": {Type: "unknown"}}}))
+	assert.NotNil(t, Configure(&Config{Fallbacks: map[string]*FallbackConfig{
+		"/api": {Type: FallbackStatic, Body: "!", Base64: true},
+	}}))
+	assert.False(t, isCircuitError(errors.New("hystrix: timeout")))
+}
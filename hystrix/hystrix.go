@@ -19,7 +19,6 @@ import (
 	"fmt"
 	"runtime"
 
-	"github.com/afex/hystrix-go/hystrix"
 	metriccollector "github.com/afex/hystrix-go/hystrix/metric_collector"
 	"trpc.group/trpc-go/trpc-go"
 	"trpc.group/trpc-go/trpc-go/filter"
@@ -39,7 +38,6 @@ var (
 	// ExcludeKey excludes prefix key.
 	ExcludeKey = "_"
 
-	cfg         map[string]hystrix.CommandConfig
 	panicBufLen = 1024
 )
 
@@ -52,14 +50,22 @@ func init() {
 
 // Setup ...
 func (p *hystrixPlugin) Setup(name string, decoder plugin.Decoder) error {
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := &Config{}
 	err := decoder.Decode(cfg)
 	if err != nil {
 		log.Errorf("decoder.Decode(%T) err(%s)", cfg, err.Error())
 		return err
 	}
-	hystrix.Configure(cfg)
+	if err := Configure(cfg); err != nil {
+		return err
+	}
+	if cfg.Watch != nil {
+		if err := watch(cfg); err != nil {
+			return err
+		}
+	}
 	filter.Register(filterName, ServerFilter(), ClientFilter())
+	registerAdmin()
 	return nil
 }
 
@@ -89,31 +95,47 @@ func RegisterCollector(collector MetricCollectorFunc) {
 func ServerFilter() filter.ServerFilter {
 	return func(ctx context.Context, req interface{}, handler filter.ServerHandleFunc) (interface{}, error) {
 		// Get routing and configuration.
-		cmd := trpc.Message(ctx).ServerRPCName()
-		if _, ok := cfg[cmd]; !ok {
-			// Configuration does not exist.
-			// Whether there is wild card symbol.
-			if _, ok := cfg[WildcardKey]; !ok {
-				// 没有直接返回
-				return handler(ctx, req)
-			}
-			// Whether to exclude parts when opening wildcards.
-			if _, ok := cfg[ExcludeKey+cmd]; ok {
-				return handler(ctx, req)
-			}
-			cmd = WildcardKey
+		msg := trpc.Message(ctx)
+		r := currentRules().resolve(msg.CalleeServiceName(), msg.ServerRPCName(), false)
+		if !r.ok {
+			return handler(ctx, req)
 		}
 
-		var rsp interface{}
-		return rsp, hystrix.Do(cmd, func() (err error) {
+		var (
+			rsp, fallbackRsp interface{}
+			fallbackErr      error
+			fallbackCalled   bool
+			hystrixFallback  func(error) error
+			guard            runGuard
+		)
+		if fallback := serverFallback(r.rpc, r.fallback); fallback != nil {
+			hystrixFallback = func(err error) error {
+				guard.close()
+				fallbackCalled = true
+				fallbackRsp, fallbackErr = fallback(ctx, req, serverError(err))
+				return fallbackErr
+			}
+		}
+		err := r.do(ctx, func(ctx context.Context) (err error) {
 			defer func() {
 				if errPanic := recover(); errPanic != nil {
 					err = recoveryHandler(ctx, errPanic)
 				}
 			}()
 			rsp, err = handler(ctx, req)
+			if err == nil {
+				guard.do(func() { storeResponse(ctx, r.rpc, r.fallback, rsp, true) })
+			}
 			return err
-		}, nil)
+		}, hystrixFallback)
+		guard.close()
+		if fallbackCalled {
+			return fallbackRsp, fallbackErr
+		}
+		if err != nil {
+			return nil, serverError(err)
+		}
+		return rsp, nil
 	}
 }
 
@@ -121,27 +143,42 @@ func ServerFilter() filter.ServerFilter {
 func ClientFilter() filter.ClientFilter {
 	return func(ctx context.Context, req, rsp interface{}, handler filter.ClientHandleFunc) error {
 		// Get routing and configuration.
-		cmd := trpc.Message(ctx).ClientRPCName()
-		if _, ok := cfg[cmd]; !ok {
-			// Configuration does not exist.
-			// Whether there is wild card symbol.
-			if _, ok := cfg[WildcardKey]; !ok {
-				// 没有直接返回
-				return handler(ctx, req, rsp)
-			}
-			//  Whether to exclude parts when opening wildcards.
-			if _, ok := cfg[ExcludeKey+cmd]; ok {
-				return handler(ctx, req, rsp)
+		msg := trpc.Message(ctx)
+		r := currentRules().resolve(msg.CalleeServiceName(), msg.ClientRPCName(), true)
+		if !r.ok {
+			return handler(ctx, req, rsp)
+		}
+
+		var (
+			fallbackErr     error
+			fallbackCalled  bool
+			hystrixFallback func(error) error
+			guard           runGuard
+		)
+		if fallback := clientFallback(r.rpc, r.fallback); fallback != nil {
+			hystrixFallback = func(err error) error {
+				guard.close()
+				fallbackCalled = true
+				fallbackErr = fallback(ctx, req, rsp, clientError(err))
+				return fallbackErr
 			}
-			cmd = WildcardKey
 		}
-		return hystrix.Do(cmd, func() (err error) {
+		err := r.do(ctx, func(ctx context.Context) (err error) {
 			defer func() {
 				if errPanic := recover(); errPanic != nil {
 					err = recoveryHandler(ctx, errPanic)
 				}
 			}()
-			return handler(ctx, req, rsp)
-		}, nil)
+			err = handler(ctx, req, rsp)
+			if err == nil {
+				guard.do(func() { storeResponse(ctx, r.rpc, r.fallback, rsp, false) })
+			}
+			return err
+		}, hystrixFallback)
+		guard.close()
+		if fallbackCalled {
+			return fallbackErr
+		}
+		return clientError(err)
 	}
 }
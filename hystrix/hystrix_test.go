@@ -16,6 +16,7 @@ package hystrix
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/errs"
 	"trpc.group/trpc-go/trpc-go/plugin"
 )
 
@@ -72,12 +74,34 @@ func testErrorHandler(ctx context.Context, req interface{}, rsp interface{}) err
 	return errors.New("rpc error")
 }
 
+// resetState drops the configs, circuits, fallbacks and responses left by the previous tests,
+// and again once the test finishes, so that the tests can be run repeatedly.
+func resetState(t *testing.T) {
+	reset := func() {
+		_ = Configure(&Config{})
+		hystrix.Flush()
+		for _, m := range []*sync.Map{&breakers, &allNodeBreakers, &responses, &responseTypes} {
+			m.Range(func(k, _ interface{}) bool {
+				m.Delete(k)
+				return true
+			})
+		}
+		fallbacks.Lock()
+		fallbacks.server = make(map[string]ServerFallbackFunc)
+		fallbacks.client = make(map[string]ClientFallbackFunc)
+		fallbacks.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
 func TestFilter_PluginType(t *testing.T) {
 	p := &hystrixPlugin{}
 	assert.Equal(t, pluginType, p.Type())
 }
 
 func TestPlugin_Setup(t *testing.T) {
+	resetState(t)
 	cfg := trpc.Config{}
 	err := yaml.Unmarshal([]byte(configInfo), &cfg)
 	assert.Nil(t, err)
@@ -89,11 +113,13 @@ func TestPlugin_Setup(t *testing.T) {
 }
 
 func TestFilterFnuc(t *testing.T) {
+	resetState(t)
 	f := ServerFilter()
 	ctx := trpc.BackgroundContext()
 	trpc.Message(ctx).WithServerRPCName("/api/test")
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := make(map[string]hystrix.CommandConfig)
 	cfg["/api/test"] = hystrix.CommandConfig{}
+	Configure(&Config{Commands: cfg})
 	_, err := f(ctx, []byte("req"), testOKServerHandler)
 	assert.Nil(t, err)
 
@@ -102,9 +128,10 @@ func TestFilterFnuc(t *testing.T) {
 }
 
 func TestClientFilter(t *testing.T) {
+	resetState(t)
 	f := ClientFilter()
 	ctx := trpc.BackgroundContext()
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := make(map[string]hystrix.CommandConfig)
 	cfg["/api/clientTest"] = hystrix.CommandConfig{
 		Timeout:                10,
 		MaxConcurrentRequests:  1000,
@@ -112,7 +139,7 @@ func TestClientFilter(t *testing.T) {
 		SleepWindow:            3,
 		ErrorPercentThreshold:  10,
 	}
-	hystrix.Configure(cfg)
+	Configure(&Config{Commands: cfg})
 	trpc.Message(ctx).WithClientRPCName("/api/clientTest")
 	// Test Panic
 	{
@@ -127,17 +154,18 @@ func TestClientFilter(t *testing.T) {
 	// Request Timeout
 	{
 		err := f(ctx, []byte("req"), []byte("rsp"), testTimeoutHandler)
-		assert.EqualError(t, err, "hystrix: timeout")
+		assertCircuitError(t, err, hystrix.ErrTimeout)
 	}
 	// Circuit is opened for reqNum >= cfg.RequestVolumeThreshold && errNum > cfg.ErrorPercentThreshold
 	{
+		waitCircuitOpen(t, "/api/clientTest")
 		err := f(ctx, []byte("req"), []byte("rsp"), testErrorHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 	// Request is rejected, because circuit is open
 	{
 		err := f(ctx, []byte("req"), []byte("rsp"), testOKHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 	// Allowing one request but getting error again, circuit keeps open
 	{
@@ -145,13 +173,14 @@ func TestClientFilter(t *testing.T) {
 		err := f(ctx, []byte("req"), []byte("rsp"), testErrorHandler)
 		assert.EqualError(t, err, "rpc error")
 		err = f(ctx, []byte("req"), []byte("rsp"), testOKHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 }
 
 func TestWildcardKeyClientFilter(t *testing.T) {
+	resetState(t)
 	f := ClientFilter()
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := make(map[string]hystrix.CommandConfig)
 	// Turn on global configuration.
 	ctx := trpc.BackgroundContext()
 	cfg["*"] = hystrix.CommandConfig{
@@ -161,7 +190,7 @@ func TestWildcardKeyClientFilter(t *testing.T) {
 		SleepWindow:            3,
 		ErrorPercentThreshold:  10,
 	}
-	hystrix.Configure(cfg)
+	Configure(&Config{Commands: cfg})
 	trpc.Message(ctx).WithClientRPCName("/api/testNil")
 	// Test Panic
 	{
@@ -176,23 +205,25 @@ func TestWildcardKeyClientFilter(t *testing.T) {
 	// Request Timeout
 	{
 		err := f(ctx, []byte("req"), []byte("rsp"), testTimeoutHandler)
-		assert.EqualError(t, err, "hystrix: timeout")
+		assertCircuitError(t, err, hystrix.ErrTimeout)
 	}
 	// Circuit is opened for reqNum >= cfg.RequestVolumeThreshold && errNum > cfg.ErrorPercentThreshold
 	{
+		waitCircuitOpen(t, "*")
 		err := f(ctx, []byte("req"), []byte("rsp"), testErrorHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 	// Request is rejected, because circuit is open
 	{
 		err := f(ctx, []byte("req"), []byte("rsp"), testOKHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 }
 
 func TestExcludeKeyClientFilter(t *testing.T) {
+	resetState(t)
 	f := ClientFilter()
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := make(map[string]hystrix.CommandConfig)
 	// Turn on global configuration.
 	ctx := trpc.BackgroundContext()
 	cfg["*"] = hystrix.CommandConfig{
@@ -210,7 +241,7 @@ func TestExcludeKeyClientFilter(t *testing.T) {
 		SleepWindow:            3,
 		ErrorPercentThreshold:  10,
 	}
-	hystrix.Configure(cfg)
+	Configure(&Config{Commands: cfg})
 
 	trpc.Message(ctx).WithClientRPCName("/api/exclude")
 	// Request OK
@@ -233,11 +264,13 @@ func TestExcludeKeyClientFilter(t *testing.T) {
 }
 
 func TestWildcardKeyServerFilter(t *testing.T) {
+	resetState(t)
 	f := ServerFilter()
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := make(map[string]hystrix.CommandConfig)
 	// Turn on global configuration.
 	ctx := trpc.BackgroundContext()
 	WildcardKey = "**"
+	defer func() { WildcardKey = "*" }()
 	cfg["**"] = hystrix.CommandConfig{
 		Timeout:                10,
 		MaxConcurrentRequests:  1000,
@@ -245,7 +278,7 @@ func TestWildcardKeyServerFilter(t *testing.T) {
 		SleepWindow:            3,
 		ErrorPercentThreshold:  10,
 	}
-	hystrix.Configure(cfg)
+	Configure(&Config{Commands: cfg})
 	trpc.Message(ctx).WithServerRPCName("/api/testNil")
 	// Test Panic
 	{
@@ -260,23 +293,25 @@ func TestWildcardKeyServerFilter(t *testing.T) {
 	// Request Timeout
 	{
 		_, err := f(ctx, []byte("req"), testTimeoutServerHandler)
-		assert.EqualError(t, err, "hystrix: timeout")
+		assertCircuitError(t, err, hystrix.ErrTimeout)
 	}
 	// Circuit is opened for reqNum >= cfg.RequestVolumeThreshold && errNum > cfg.ErrorPercentThreshold
 	{
+		waitCircuitOpen(t, "**")
 		_, err := f(ctx, []byte("req"), testErrorServerHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 	// Request is rejected, because circuit is open
 	{
 		_, err := f(ctx, []byte("req"), testOKServerHandler)
-		assert.EqualError(t, err, "hystrix: circuit open")
+		assertCircuitError(t, err, hystrix.ErrCircuitOpen)
 	}
 }
 
 func TestExcludeKeyServerFilter(t *testing.T) {
+	resetState(t)
 	f := ServerFilter()
-	cfg = make(map[string]hystrix.CommandConfig)
+	cfg := make(map[string]hystrix.CommandConfig)
 	// Turn on global configuration.
 	ctx := trpc.BackgroundContext()
 	cfg["*"] = hystrix.CommandConfig{
@@ -294,7 +329,7 @@ func TestExcludeKeyServerFilter(t *testing.T) {
 		SleepWindow:            3,
 		ErrorPercentThreshold:  10,
 	}
-	hystrix.Configure(cfg)
+	Configure(&Config{Commands: cfg})
 
 	trpc.Message(ctx).WithServerRPCName("/api/exclude")
 	// Request OK
@@ -316,6 +351,24 @@ func TestExcludeKeyServerFilter(t *testing.T) {
 	}
 }
 
+// waitCircuitOpen waits for hystrix-go, which counts the results asynchronously, to open the circuit.
+func waitCircuitOpen(t *testing.T, name string) {
+	assert.Eventually(t, func() bool {
+		c, _, err := hystrix.GetCircuit(name)
+		return err == nil && c.IsOpen()
+	}, time.Second, time.Millisecond)
+}
+
+func assertCircuitError(t *testing.T, err error, circuitErr hystrix.CircuitError) {
+	assert.ErrorIs(t, err, circuitErr)
+	switch circuitErr {
+	case hystrix.ErrTimeout:
+		assert.True(t, errs.Code(err) == errs.RetServerTimeout || errs.Code(err) == errs.RetClientTimeout, err)
+	default:
+		assert.True(t, errs.Code(err) == errs.RetServerOverload || errs.Code(err) == errs.RetClientOverload, err)
+	}
+}
+
 type testMetricCollector struct{}
 
 // Update ...
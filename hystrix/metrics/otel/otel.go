@@ -0,0 +1,157 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+// Package otel exports the metrics and circuit states of hystrix commands to opentelemetry.
+package otel
+
+import (
+	"context"
+
+	metriccollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/asyncint64"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+
+	"trpc.group/trpc-go/trpc-filter/hystrix"
+)
+
+const (
+	keyCommand = attribute.Key("command")
+	keyNode    = attribute.Key("node")
+	keyBreaker = attribute.Key("breaker")
+)
+
+// counters are the events counted for each command execution.
+var counters = []struct {
+	name  string
+	desc  string
+	value func(metriccollector.MetricResult) float64
+}{
+	{"hystrix_attempts_total", "num of command executions",
+		func(r metriccollector.MetricResult) float64 { return r.Attempts }},
+	{"hystrix_errors_total", "num of executions which end with an error, including rejections",
+		func(r metriccollector.MetricResult) float64 { return r.Errors }},
+	{"hystrix_successes_total", "num of successful executions",
+		func(r metriccollector.MetricResult) float64 { return r.Successes }},
+	{"hystrix_failures_total", "num of failed executions",
+		func(r metriccollector.MetricResult) float64 { return r.Failures }},
+	{"hystrix_rejects_total", "num of executions rejected by max concurrency",
+		func(r metriccollector.MetricResult) float64 { return r.Rejects }},
+	{"hystrix_short_circuits_total", "num of executions rejected by open circuit",
+		func(r metriccollector.MetricResult) float64 { return r.ShortCircuits }},
+	{"hystrix_timeouts_total", "num of timed out executions",
+		func(r metriccollector.MetricResult) float64 { return r.Timeouts }},
+	{"hystrix_fallback_successes_total", "num of successful fallbacks",
+		func(r metriccollector.MetricResult) float64 { return r.FallbackSuccesses }},
+	{"hystrix_fallback_failures_total", "num of failed fallbacks",
+		func(r metriccollector.MetricResult) float64 { return r.FallbackFailures }},
+}
+
+// Collector exports the metrics of hystrix commands, with the command name as attribute.
+// Commands of per-node breakers are named as "rpc@address".
+type Collector struct {
+	counters      []syncint64.Counter
+	runDuration   syncint64.Histogram
+	totalDuration syncint64.Histogram
+	concurrency   syncfloat64.Histogram
+	state         asyncint64.Gauge
+}
+
+// NewCollector creates the instruments of meter, and observes the circuit states on each collection.
+// Its NewMetricCollector should be registered by hystrix.RegisterCollector, Register does both.
+func NewCollector(meter metric.Meter) (*Collector, error) {
+	c := &Collector{}
+	for _, counter := range counters {
+		inst, err := meter.SyncInt64().Counter(counter.name,
+			instrument.WithDescription(counter.desc),
+			instrument.WithUnit(unit.Dimensionless))
+		if err != nil {
+			return nil, err
+		}
+		c.counters = append(c.counters, inst)
+	}
+	var err error
+	if c.runDuration, err = meter.SyncInt64().Histogram("hystrix_run_duration",
+		instrument.WithDescription("duration of running the command"),
+		instrument.WithUnit(unit.Milliseconds)); err != nil {
+		return nil, err
+	}
+	if c.totalDuration, err = meter.SyncInt64().Histogram("hystrix_total_duration",
+		instrument.WithDescription("duration of the execution, including waiting for the fallback"),
+		instrument.WithUnit(unit.Milliseconds)); err != nil {
+		return nil, err
+	}
+	if c.concurrency, err = meter.SyncFloat64().Histogram("hystrix_concurrency_in_use",
+		instrument.WithDescription("ratio of running executions to max concurrent requests"),
+		instrument.WithUnit(unit.Dimensionless)); err != nil {
+		return nil, err
+	}
+	if c.state, err = meter.AsyncInt64().Gauge("hystrix_circuit_state",
+		instrument.WithDescription("state of the circuit, 0 for closed, 1 for open, 2 for half-open and 3 for unknown"),
+		instrument.WithUnit(unit.Dimensionless)); err != nil {
+		return nil, err
+	}
+	if err := meter.RegisterCallback([]instrument.Asynchronous{c.state}, c.observeStates); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Register creates a collector of meter and registers it to hystrix.
+func Register(meter metric.Meter) error {
+	c, err := NewCollector(meter)
+	if err != nil {
+		return err
+	}
+	hystrix.RegisterCollector(c.NewMetricCollector)
+	return nil
+}
+
+func (c *Collector) observeStates(ctx context.Context) {
+	for _, s := range hystrix.Circuits() {
+		c.state.Observe(ctx, int64(s.State),
+			keyCommand.String(s.Name), keyNode.String(s.Node), keyBreaker.String(s.Breaker))
+	}
+}
+
+// NewMetricCollector implements hystrix.MetricCollectorFunc.
+func (c *Collector) NewMetricCollector(name string) metriccollector.MetricCollector {
+	return &metricCollector{c: c, attr: keyCommand.String(name)}
+}
+
+// metricCollector is the collector of one command.
+type metricCollector struct {
+	c    *Collector
+	attr attribute.KeyValue
+}
+
+// Update implements metriccollector.MetricCollector.
+func (m *metricCollector) Update(r metriccollector.MetricResult) {
+	ctx := context.Background()
+	for i, counter := range counters {
+		if v := int64(counter.value(r)); v > 0 {
+			m.c.counters[i].Add(ctx, v, m.attr)
+		}
+	}
+	m.c.runDuration.Record(ctx, r.RunDuration.Milliseconds(), m.attr)
+	m.c.totalDuration.Record(ctx, r.TotalDuration.Milliseconds(), m.attr)
+	m.c.concurrency.Record(ctx, r.ConcurrencyInUse, m.attr)
+}
+
+// Reset implements metriccollector.MetricCollector.
+// Opentelemetry counters are cumulative, so nothing is reset.
+func (m *metricCollector) Reset() {}
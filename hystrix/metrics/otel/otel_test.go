@@ -0,0 +1,73 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	afex "github.com/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metrictest"
+	"trpc.group/trpc-go/trpc-go"
+
+	"trpc.group/trpc-go/trpc-filter/hystrix"
+	"trpc.group/trpc-go/trpc-filter/hystrix/metrics/otel"
+)
+
+// runs makes the command name unique to each run of the test, since the circuits outlive the test.
+var runs int
+
+func TestCollector(t *testing.T) {
+	runs++
+	cmd := fmt.Sprintf("/api/otel%d", runs)
+	provider, exporter := metrictest.NewTestMeterProvider()
+	assert.Nil(t, otel.Register(provider.Meter("hystrix")))
+	assert.Nil(t, hystrix.Configure(&hystrix.Config{
+		Breaker: hystrix.BreakerNative,
+		Commands: map[string]afex.CommandConfig{
+			cmd: {RequestVolumeThreshold: 1, SleepWindow: 1000},
+		},
+	}))
+	defer hystrix.Configure(&hystrix.Config{})
+
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName(cmd)
+	f := hystrix.ClientFilter()
+	assert.Nil(t, f(ctx, nil, nil, func(context.Context, interface{}, interface{}) error { return nil }))
+	assert.NotNil(t, f(ctx, nil, nil, func(context.Context, interface{}, interface{}) error {
+		return errors.New("unknown")
+	}))
+	assert.NotNil(t, f(ctx, nil, nil, func(context.Context, interface{}, interface{}) error { return nil }))
+
+	assert.Nil(t, exporter.Collect(context.Background()))
+	attrs := []attribute.KeyValue{attribute.String("command", cmd)}
+	for name, want := range map[string]int64{
+		"hystrix_attempts_total":       3,
+		"hystrix_successes_total":      1,
+		"hystrix_failures_total":       1,
+		"hystrix_short_circuits_total": 1,
+	} {
+		r, err := exporter.GetByNameAndAttributes(name, attrs)
+		assert.Nil(t, err, name)
+		assert.Equal(t, want, r.Sum.AsInt64(), name)
+	}
+	r, err := exporter.GetByNameAndAttributes("hystrix_circuit_state", append(attrs,
+		attribute.String("breaker", hystrix.BreakerNative)))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(hystrix.StateOpen), r.LastValue.AsInt64())
+}
@@ -0,0 +1,169 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+// Package prom exports the metrics and circuit states of hystrix commands to prometheus.
+package prom
+
+import (
+	metriccollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"trpc.group/trpc-go/trpc-filter/hystrix"
+)
+
+const (
+	nameSpace    = "hystrix"
+	labelCommand = "command"
+)
+
+// counters are the events counted for each command execution.
+var counters = []struct {
+	name  string
+	help  string
+	value func(metriccollector.MetricResult) float64
+}{
+	{"attempts_total", "num of command executions",
+		func(r metriccollector.MetricResult) float64 { return r.Attempts }},
+	{"errors_total", "num of executions which end with an error, including rejections",
+		func(r metriccollector.MetricResult) float64 { return r.Errors }},
+	{"successes_total", "num of successful executions",
+		func(r metriccollector.MetricResult) float64 { return r.Successes }},
+	{"failures_total", "num of failed executions",
+		func(r metriccollector.MetricResult) float64 { return r.Failures }},
+	{"rejects_total", "num of executions rejected by max concurrency",
+		func(r metriccollector.MetricResult) float64 { return r.Rejects }},
+	{"short_circuits_total", "num of executions rejected by open circuit",
+		func(r metriccollector.MetricResult) float64 { return r.ShortCircuits }},
+	{"timeouts_total", "num of timed out executions",
+		func(r metriccollector.MetricResult) float64 { return r.Timeouts }},
+	{"fallback_successes_total", "num of successful fallbacks",
+		func(r metriccollector.MetricResult) float64 { return r.FallbackSuccesses }},
+	{"fallback_failures_total", "num of failed fallbacks",
+		func(r metriccollector.MetricResult) float64 { return r.FallbackFailures }},
+}
+
+// Collector exports the metrics of hystrix commands, labeled by command name.
+// Commands of per-node breakers are named as "rpc@address".
+type Collector struct {
+	counters      []*prometheus.CounterVec
+	runDuration   *prometheus.HistogramVec
+	totalDuration *prometheus.HistogramVec
+	concurrency   *prometheus.GaugeVec
+	state         *prometheus.Desc
+}
+
+// NewCollector creates a collector. It should be registered to prometheus,
+// and its NewMetricCollector should be registered by hystrix.RegisterCollector.
+// Register does both.
+func NewCollector() *Collector {
+	c := &Collector{
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: nameSpace,
+			Name:      "run_duration_seconds",
+			Help:      "duration of running the command",
+		}, []string{labelCommand}),
+		totalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: nameSpace,
+			Name:      "total_duration_seconds",
+			Help:      "duration of the execution, including waiting for the fallback",
+		}, []string{labelCommand}),
+		concurrency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: nameSpace,
+			Name:      "concurrency_in_use",
+			Help:      "ratio of running executions to max concurrent requests",
+		}, []string{labelCommand}),
+		state: prometheus.NewDesc(prometheus.BuildFQName(nameSpace, "", "circuit_state"),
+			"state of the circuit, 0 for closed, 1 for open, 2 for half-open and 3 for unknown",
+			[]string{labelCommand, "node", "breaker"}, nil),
+	}
+	for _, counter := range counters {
+		c.counters = append(c.counters, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: nameSpace,
+			Name:      counter.name,
+			Help:      counter.help,
+		}, []string{labelCommand}))
+	}
+	return c
+}
+
+// Register creates a collector, registers it to reg and to hystrix.
+func Register(reg prometheus.Registerer) error {
+	c := NewCollector()
+	if err := reg.Register(c); err != nil {
+		return err
+	}
+	hystrix.RegisterCollector(c.NewMetricCollector)
+	return nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, counter := range c.counters {
+		counter.Describe(ch)
+	}
+	c.runDuration.Describe(ch)
+	c.totalDuration.Describe(ch)
+	c.concurrency.Describe(ch)
+	ch <- c.state
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, counter := range c.counters {
+		counter.Collect(ch)
+	}
+	c.runDuration.Collect(ch)
+	c.totalDuration.Collect(ch)
+	c.concurrency.Collect(ch)
+	for _, s := range hystrix.Circuits() {
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, float64(s.State),
+			s.Name, s.Node, s.Breaker)
+	}
+}
+
+// NewMetricCollector implements hystrix.MetricCollectorFunc.
+func (c *Collector) NewMetricCollector(name string) metriccollector.MetricCollector {
+	m := &metricCollector{
+		runDuration:   c.runDuration.WithLabelValues(name),
+		totalDuration: c.totalDuration.WithLabelValues(name),
+		concurrency:   c.concurrency.WithLabelValues(name),
+	}
+	for _, counter := range c.counters {
+		m.counters = append(m.counters, counter.WithLabelValues(name))
+	}
+	return m
+}
+
+// metricCollector is the collector of one command.
+type metricCollector struct {
+	counters      []prometheus.Counter
+	runDuration   prometheus.Observer
+	totalDuration prometheus.Observer
+	concurrency   prometheus.Gauge
+}
+
+// Update implements metriccollector.MetricCollector.
+func (m *metricCollector) Update(r metriccollector.MetricResult) {
+	for i, counter := range counters {
+		if v := counter.value(r); v > 0 {
+			m.counters[i].Add(v)
+		}
+	}
+	m.runDuration.Observe(r.RunDuration.Seconds())
+	m.totalDuration.Observe(r.TotalDuration.Seconds())
+	m.concurrency.Set(r.ConcurrencyInUse)
+}
+
+// Reset implements metriccollector.MetricCollector.
+// Prometheus counters are cumulative, so nothing is reset.
+func (m *metricCollector) Reset() {}
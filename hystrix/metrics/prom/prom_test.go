@@ -0,0 +1,76 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package prom_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	afex "github.com/afex/hystrix-go/hystrix"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+
+	"trpc.group/trpc-go/trpc-filter/hystrix"
+	"trpc.group/trpc-go/trpc-filter/hystrix/metrics/prom"
+)
+
+// runs makes the command name unique to each run of the test, since the circuits outlive the test.
+var runs int
+
+func TestCollector(t *testing.T) {
+	runs++
+	cmd := fmt.Sprintf("/api/prom%d", runs)
+	reg := prometheus.NewRegistry()
+	assert.Nil(t, prom.Register(reg))
+	assert.Nil(t, hystrix.Configure(&hystrix.Config{
+		Breaker: hystrix.BreakerNative,
+		Commands: map[string]afex.CommandConfig{
+			cmd: {RequestVolumeThreshold: 1, SleepWindow: 1000},
+		},
+	}))
+	defer hystrix.Configure(&hystrix.Config{})
+
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName(cmd)
+	f := hystrix.ClientFilter()
+	assert.Nil(t, f(ctx, nil, nil, func(context.Context, interface{}, interface{}) error { return nil }))
+	assert.NotNil(t, f(ctx, nil, nil, func(context.Context, interface{}, interface{}) error {
+		return errors.New("unknown")
+	}))
+	assert.NotNil(t, f(ctx, nil, nil, func(context.Context, interface{}, interface{}) error { return nil }))
+
+	assert.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+# HELP hystrix_attempts_total num of command executions
+# TYPE hystrix_attempts_total counter
+hystrix_attempts_total{command="%[1]s"} 3
+# HELP hystrix_successes_total num of successful executions
+# TYPE hystrix_successes_total counter
+hystrix_successes_total{command="%[1]s"} 1
+# HELP hystrix_failures_total num of failed executions
+# TYPE hystrix_failures_total counter
+hystrix_failures_total{command="%[1]s"} 1
+# HELP hystrix_short_circuits_total num of executions rejected by open circuit
+# TYPE hystrix_short_circuits_total counter
+hystrix_short_circuits_total{command="%[1]s"} 1
+# HELP hystrix_circuit_state state of the circuit, 0 for closed, 1 for open, 2 for half-open and 3 for unknown
+# TYPE hystrix_circuit_state gauge
+hystrix_circuit_state{breaker="native",command="%[1]s",node=""} 1
+`, cmd)), "hystrix_attempts_total", "hystrix_successes_total", "hystrix_failures_total",
+		"hystrix_short_circuits_total", "hystrix_circuit_state"))
+}
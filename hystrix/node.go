@@ -0,0 +1,120 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/naming/bannednodes"
+	"trpc.group/trpc-go/trpc-go/naming/registry"
+)
+
+// nodeBreakers holds the native breakers of the callee nodes of a client command.
+// The node of a call is selected after the filter, so the breakers can not reject calls in advance.
+// Instead, nodes with open circuits are banned from the selector through bannednodes,
+// and the result of each call is recorded to the breaker of the selected node.
+type nodeBreakers struct {
+	cmd      string
+	mu       sync.RWMutex
+	settings *breakerSettings
+	nodes    map[string]*breaker
+}
+
+// allNodeBreakers maps command names to their node breakers.
+var allNodeBreakers sync.Map // map[string]*nodeBreakers
+
+// configureNodeBreakers creates or updates the node breakers of a command.
+func configureNodeBreakers(cmd string, cfg hystrix.CommandConfig, native NativeConfig) *nodeBreakers {
+	s := newBreakerSettings(cfg, native)
+	v, loaded := allNodeBreakers.LoadOrStore(cmd, &nodeBreakers{
+		cmd:      cmd,
+		settings: s,
+		nodes:    make(map[string]*breaker),
+	})
+	nb := v.(*nodeBreakers)
+	if loaded {
+		nb.mu.Lock()
+		nb.settings = s
+		for _, b := range nb.nodes {
+			b.settings.Store(s)
+		}
+		nb.mu.Unlock()
+	}
+	return nb
+}
+
+// get returns the breaker of the node address, creating it if not exist.
+func (nb *nodeBreakers) get(addr string) *breaker {
+	nb.mu.RLock()
+	b, ok := nb.nodes[addr]
+	nb.mu.RUnlock()
+	if ok {
+		return b
+	}
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+	if b, ok := nb.nodes[addr]; ok {
+		return b
+	}
+	b = newBreaker(nb.cmd+"@"+addr, nb.settings)
+	nb.nodes[addr] = b
+	return b
+}
+
+// unavailable returns the nodes whose circuits reject calls.
+func (nb *nodeBreakers) unavailable() []*registry.Node {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+	var nodes []*registry.Node
+	for addr, b := range nb.nodes {
+		if !b.available() {
+			nodes = append(nodes, &registry.Node{Address: addr})
+		}
+	}
+	return nodes
+}
+
+// do bans the unavailable nodes, runs fn and records its result to the breaker of the selected node.
+// If all nodes are banned, the selector still picks one of them, so that the call is not failed by breakers.
+func (nb *nodeBreakers) do(ctx context.Context, fn func(context.Context) error, fallback func(error) error) error {
+	if banned := nb.unavailable(); len(banned) > 0 {
+		if _, _, ok := bannednodes.FromCtx(ctx); !ok {
+			ctx = bannednodes.NewCtx(ctx, false)
+		}
+		bannednodes.Add(ctx, banned...)
+	}
+	nb.mu.RLock()
+	timeout := nb.settings.timeout
+	nb.mu.RUnlock()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := fn(ctx)
+	elapsed := time.Since(start)
+	addr := codec.Message(ctx).RemoteAddr()
+	if addr == nil {
+		return callFallback(err, fallback)
+	}
+	b := nb.get(addr.String())
+	b.record(err, elapsed)
+	return b.finish(runResult(ctx, b.loadSettings(), err, elapsed), start, err, fallback)
+}
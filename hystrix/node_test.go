@@ -0,0 +1,90 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/naming/bannednodes"
+	"trpc.group/trpc-go/trpc-go/naming/registry"
+)
+
+func bannedAddrs(ctx context.Context) []string {
+	nodes, _, ok := bannednodes.FromCtx(ctx)
+	if !ok {
+		return nil
+	}
+	var addrs []string
+	nodes.Range(func(n *registry.Node) bool {
+		addrs = append(addrs, n.Address)
+		return true
+	})
+	return addrs
+}
+
+func nodeHandler(addr string, err error, banned *[]string) func(ctx context.Context, req, rsp interface{}) error {
+	return func(ctx context.Context, req, rsp interface{}) error {
+		*banned = bannedAddrs(ctx)
+		trpc.Message(ctx).WithRemoteAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8000 + len(addr)})
+		return err
+	}
+}
+
+func TestPerNodeClientFilter(t *testing.T) {
+	resetState(t)
+	assert.Nil(t, Configure(&Config{
+		PerNode: true,
+		Commands: map[string]hystrix.CommandConfig{
+			"/api/node": {RequestVolumeThreshold: 1, SleepWindow: 1000},
+		},
+	}))
+	f := ClientFilter()
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName("/api/node")
+
+	var banned []string
+	netErr := errs.NewFrameError(errs.RetClientNetErr, "network error")
+	// Node 127.0.0.1:8001 breaks.
+	assert.Equal(t, netErr, f(ctx, nil, nil, nodeHandler("a", netErr, &banned)))
+	assert.Empty(t, banned)
+	// The broken node is banned, while the call is not failed by the breaker.
+	assert.Nil(t, f(ctx, nil, nil, nodeHandler("bb", nil, &banned)))
+	assert.Equal(t, []string{"127.0.0.1:8001"}, banned)
+	// Even if the selector has to pick the broken node.
+	assert.Nil(t, f(ctx, nil, nil, nodeHandler("a", nil, &banned)))
+	assert.Equal(t, []string{"127.0.0.1:8001"}, banned)
+
+	v, ok := allNodeBreakers.Load("/api/node")
+	assert.True(t, ok)
+	nb := v.(*nodeBreakers)
+	assert.Equal(t, StateOpen, nb.get("127.0.0.1:8001").State())
+	assert.Equal(t, StateClosed, nb.get("127.0.0.1:8002").State())
+
+	// Server calls are not keyed by node.
+	assert.Nil(t, currentRules().resolve("", "/api/node", false).nodes)
+}
+
+func TestNodeBreakers_Reconfigure(t *testing.T) {
+	resetState(t)
+	nb := configureNodeBreakers("/api/nodeReconfigure", hystrix.CommandConfig{Timeout: 10}, NativeConfig{})
+	b := nb.get("127.0.0.1:8000")
+	assert.Same(t, nb, configureNodeBreakers("/api/nodeReconfigure", hystrix.CommandConfig{Timeout: 20}, NativeConfig{}))
+	assert.Equal(t, int64(20), b.loadSettings().timeout.Milliseconds())
+}
@@ -0,0 +1,105 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package hystrix
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"trpc.group/trpc-go/trpc-go/config"
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+const (
+	defaultWatchProvider = "file"
+	defaultWatchCodec    = "yaml"
+)
+
+// watch loads the command configs from the provider and reloads them on change.
+// base holds the configs from plugin setup, the watched configs override them by key.
+func watch(base *Config) error {
+	w := base.Watch
+	providerName, codecName := w.Provider, w.Codec
+	if providerName == "" {
+		providerName = defaultWatchProvider
+	}
+	if codecName == "" {
+		codecName = defaultWatchCodec
+	}
+	provider := config.GetProvider(providerName)
+	if provider == nil {
+		return fmt.Errorf("hystrix: config provider %s not registered", providerName)
+	}
+	codec := config.GetCodec(codecName)
+	if codec == nil {
+		return fmt.Errorf("hystrix: config codec %s not registered", codecName)
+	}
+
+	load := func(data []byte) error {
+		var watched Config
+		if err := codec.Unmarshal(data, &watched); err != nil {
+			return fmt.Errorf("hystrix: unmarshal watched config %s err: %w", w.Path, err)
+		}
+		return Configure(mergeConfig(base, &watched))
+	}
+	data, err := provider.Read(w.Path)
+	if err != nil {
+		return fmt.Errorf("hystrix: read watched config %s err: %w", w.Path, err)
+	}
+	if err := load(data); err != nil {
+		return err
+	}
+	provider.Watch(func(path string, data []byte) {
+		if path != w.Path {
+			return
+		}
+		if err := load(data); err != nil {
+			log.Errorf("%v, keep the previous config", err)
+			return
+		}
+		log.Infof("hystrix: config reloaded from %s", w.Path)
+	})
+	return nil
+}
+
+// mergeConfig returns a new config with the fields of override taking precedence over base.
+func mergeConfig(base, override *Config) *Config {
+	merged := &Config{
+		Breaker:   base.Breaker,
+		Native:    base.Native,
+		Services:  make(map[string]hystrix.CommandConfig, len(base.Services)+len(override.Services)),
+		Fallbacks: make(map[string]*FallbackConfig, len(base.Fallbacks)+len(override.Fallbacks)),
+		Commands:  make(map[string]hystrix.CommandConfig, len(base.Commands)+len(override.Commands)),
+	}
+	merged.PerNode = base.PerNode || override.PerNode
+	if override.Breaker != "" {
+		merged.Breaker = override.Breaker
+	}
+	if !reflect.DeepEqual(override.Native, NativeConfig{}) {
+		merged.Native = override.Native
+	}
+	for _, src := range []*Config{base, override} {
+		for k, v := range src.Services {
+			merged.Services[k] = v
+		}
+		for k, v := range src.Fallbacks {
+			merged.Fallbacks[k] = v
+		}
+		for k, v := range src.Commands {
+			merged.Commands[k] = v
+		}
+	}
+	return merged
+}
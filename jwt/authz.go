@@ -0,0 +1,191 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 默认的 scope 及 role 声明名
+const (
+	defaultScopeClaim = "scope"
+	defaultRoleClaim  = "roles"
+)
+
+// ErrPermissionDenied 鉴权不通过
+var ErrPermissionDenied = fmt.Errorf("permission denied")
+
+// Authorization 基于 claims 的方法级鉴权配置
+type Authorization struct {
+	Policies   []Policy `yaml:"policies"`    // 鉴权策略, 按顺序使用第一个匹配的策略, 没有匹配的策略时不鉴权
+	ScopeClaim string   `yaml:"scope_claim"` // scope 声明名, 默认 scope, 值为空格分隔的字符串或数组
+	RoleClaim  string   `yaml:"role_claim"`  // role 声明名, 默认 roles, 值为空格分隔的字符串或数组
+}
+
+// Policy 鉴权策略
+type Policy struct {
+	Match         []string         `yaml:"match"`          // 匹配的 RPC 名或 HTTP path, 支持通配符 * 及 ?
+	RequireScopes []string         `yaml:"require_scopes"` // 必需的 scope, 需全部具备
+	RequireRoles  []string         `yaml:"require_roles"`  // 必需的 role, 具备其中之一即可
+	Claims        []ClaimCondition `yaml:"claims"`         // 声明条件, 需全部满足
+}
+
+// ClaimCondition 声明条件, 非标准声明在自定义数据中查找, 嵌套字段以 . 分隔, 如 org.id
+type ClaimCondition struct {
+	Claim    string `yaml:"claim"`    // 声明名
+	Equals   string `yaml:"equals"`   // 声明值等于该值
+	Contains string `yaml:"contains"` // 声明值为数组或空格分隔的字符串时, 包含该值
+}
+
+// match 返回第一个匹配 names 之一的策略
+func (a *Authorization) match(names ...string) *Policy {
+	for i := range a.Policies {
+		p := &a.Policies[i]
+		for _, pattern := range p.Match {
+			for _, name := range names {
+				if globMatch(pattern, name) {
+					return p
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// authorize 校验匹配 names 的策略
+func (a *Authorization) authorize(c *Claims, names ...string) error {
+	p := a.match(names...)
+	if p == nil {
+		return nil
+	}
+	scopeClaim, roleClaim := a.ScopeClaim, a.RoleClaim
+	if scopeClaim == "" {
+		scopeClaim = defaultScopeClaim
+	}
+	if roleClaim == "" {
+		roleClaim = defaultRoleClaim
+	}
+	if len(p.RequireScopes) > 0 {
+		scopes := c.values(scopeClaim)
+		for _, scope := range p.RequireScopes {
+			if !contains(scopes, scope) {
+				return fmt.Errorf("%w: missing scope %q", ErrPermissionDenied, scope)
+			}
+		}
+	}
+	if len(p.RequireRoles) > 0 && !containsAny(p.RequireRoles, c.values(roleClaim)) {
+		return fmt.Errorf("%w: require one of roles %q", ErrPermissionDenied, p.RequireRoles)
+	}
+	for _, cond := range p.Claims {
+		if err := cond.check(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cond *ClaimCondition) check(c *Claims) error {
+	v, ok := c.value(cond.Claim)
+	if !ok {
+		return fmt.Errorf("%w: missing claim %q", ErrPermissionDenied, cond.Claim)
+	}
+	if cond.Equals != "" && fmt.Sprint(v) != cond.Equals {
+		return fmt.Errorf("%w: claim %q does not equal %q", ErrPermissionDenied, cond.Claim, cond.Equals)
+	}
+	if cond.Contains != "" && !contains(toStrings(v), cond.Contains) {
+		return fmt.Errorf("%w: claim %q does not contain %q", ErrPermissionDenied, cond.Claim, cond.Contains)
+	}
+	return nil
+}
+
+// value 按名称获取声明值, 非标准声明在自定义数据中查找
+func (c *Claims) value(name string) (interface{}, bool) {
+	switch name {
+	case ClaimIssuer:
+		return c.Issuer, c.Issuer != ""
+	case ClaimSubject:
+		return c.Subject, c.Subject != ""
+	case ClaimAudience:
+		return c.Audience, len(c.Audience) > 0
+	case ClaimID:
+		return c.ID, c.ID != ""
+	}
+	var v interface{} = c.Custom
+	for _, key := range strings.Split(name, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// values 获取数组或空格分隔字符串形式的声明值
+func (c *Claims) values(name string) []string {
+	v, ok := c.value(name)
+	if !ok {
+		return nil
+	}
+	return toStrings(v)
+}
+
+func toStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		ss := make([]string, 0, len(v))
+		for _, e := range v {
+			ss = append(ss, fmt.Sprint(e))
+		}
+		return ss
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// isGlob 是否包含通配符
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?")
+}
+
+// globMatch pattern 是否匹配 s, * 匹配任意字符序列(包括 /), ? 匹配任意单个字符
+func globMatch(pattern, s string) bool {
+	var p, i, starP, starI = 0, 0, -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] != '*' && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starI = p, i
+			p++
+		case starP >= 0:
+			starI++
+			p, i = starP+1, starI
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,118 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+func TestGlobMatch(t *testing.T) {
+	assert.True(t, globMatch("/trpc.app.server.Admin/*", "/trpc.app.server.Admin/Delete"))
+	assert.True(t, globMatch("/v1/admin/*", "/v1/admin/users/1"))
+	assert.True(t, globMatch("/v?/login", "/v2/login"))
+	assert.True(t, globMatch("*", ""))
+	assert.False(t, globMatch("/v1/admin/*", "/v1/user"))
+	assert.False(t, globMatch("/v?/login", "/v10/login"))
+}
+
+func TestAuthorization(t *testing.T) {
+	a := &Authorization{Policies: []Policy{
+		{Match: []string{"/trpc.app.server.Admin/Delete*"}, RequireScopes: []string{"admin:read", "admin:write"}},
+		{Match: []string{"/trpc.app.server.Admin/*"}, RequireRoles: []string{"admin", "ops"}},
+		{Match: []string{"/v1/tenant/*"}, Claims: []ClaimCondition{
+			{Claim: "org.id", Equals: "42"},
+			{Claim: "groups", Contains: "dev"},
+		}},
+		{Match: []string{"/v1/self"}, Claims: []ClaimCondition{{Claim: ClaimSubject, Equals: "10001"}}},
+	}}
+	c := &Claims{Subject: "10001", Custom: map[string]interface{}{
+		"scope":  "admin:read profile",
+		"roles":  []interface{}{"ops"},
+		"org":    map[string]interface{}{"id": float64(42)},
+		"groups": []interface{}{"dev", "qa"},
+	}}
+	// No matching policy.
+	assert.Nil(t, a.authorize(c, "/trpc.app.server.Greeter/Hello"))
+	// The first matching policy applies.
+	err := a.authorize(c, "/trpc.app.server.Admin/DeleteUser")
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+	assert.Contains(t, err.Error(), `missing scope "admin:write"`)
+	assert.Nil(t, a.authorize(c, "/trpc.app.server.Admin/ListUsers"))
+	assert.Nil(t, a.authorize(c, "/v1/tenant/users"))
+	assert.Nil(t, a.authorize(c, "/v1/self"))
+
+	c.Custom.(map[string]interface{})["scope"] = []interface{}{"admin:read", "admin:write"}
+	assert.Nil(t, a.authorize(c, "/trpc.app.server.Admin/DeleteUser"))
+
+	c.Custom.(map[string]interface{})["roles"] = "guest"
+	err = a.authorize(c, "/trpc.app.server.Admin/ListUsers")
+	assert.Contains(t, err.Error(), "require one of roles")
+
+	c.Custom.(map[string]interface{})["groups"] = []interface{}{"qa"}
+	err = a.authorize(c, "/v1/tenant/users")
+	assert.Contains(t, err.Error(), `claim "groups" does not contain "dev"`)
+
+	delete(c.Custom.(map[string]interface{}), "org")
+	err = a.authorize(c, "/v1/tenant/users")
+	assert.Contains(t, err.Error(), `missing claim "org.id"`)
+
+	c.Subject = "10002"
+	err = a.authorize(c, "/v1/self")
+	assert.Contains(t, err.Error(), `claim "sub" does not equal "10001"`)
+
+	// Custom claim names.
+	a = &Authorization{ScopeClaim: "scp", RoleClaim: "role", Policies: []Policy{
+		{Match: []string{"*"}, RequireScopes: []string{"read"}, RequireRoles: []string{"admin"}},
+	}}
+	c = &Claims{Custom: map[string]interface{}{"scp": []interface{}{"read"}, "role": "admin"}}
+	assert.Nil(t, a.authorize(c, "/any"))
+}
+
+func TestServerFilter_Authorization(t *testing.T) {
+	sign := mockSigner()
+	SetDefaultSigner(sign)
+	token, err := sign.Sign(map[string]interface{}{"roles": []string{"admin"}})
+	assert.Nil(t, err)
+	f := ServerFilter(
+		WithExcludePathSet(map[string]bool{"/trpc.app.server.Greeter/Login*": true}),
+		WithTokenSources(TokenSource{Type: SourceMetadata, Name: "authorization"}),
+		WithAuthorization(&Authorization{Policies: []Policy{
+			{Match: []string{"/trpc.app.server.Admin/*"}, RequireRoles: []string{"admin"}},
+			{Match: []string{"/trpc.app.server.Greeter/*"}, RequireScopes: []string{"greet"}},
+		}}))
+
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithServerMetaData(codec.MetaData{"authorization": []byte(token)})
+	msg.WithServerRPCName("/trpc.app.server.Admin/Delete")
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+
+	msg.WithServerRPCName("/trpc.app.server.Greeter/Hello")
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Equal(t, errs.RetServerAuthFail, errs.Code(err))
+	assert.Contains(t, errs.Msg(err), `missing scope "greet"`)
+
+	// Excluded by glob, neither token nor policy is checked.
+	msg.WithServerMetaData(nil)
+	msg.WithServerRPCName("/trpc.app.server.Greeter/LoginByPassword")
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+}
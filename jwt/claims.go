@@ -0,0 +1,234 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 标准声明名, 用于配置必需的声明
+const (
+	ClaimIssuer    = "iss"
+	ClaimSubject   = "sub"
+	ClaimAudience  = "aud"
+	ClaimExpiresAt = "exp"
+	ClaimNotBefore = "nbf"
+	ClaimIssuedAt  = "iat"
+	ClaimID        = "jti"
+)
+
+// token 类型
+const (
+	TokenTypeAccess  = "access"  // 访问 token
+	TokenTypeRefresh = "refresh" // 刷新 token, 只能用于换取新的 token
+	TokenTypeService = "service" // 服务间 token, 由客户端拦截器签发
+)
+
+var (
+	// ErrInvalidIssuer iss 不是预期的签发者
+	ErrInvalidIssuer = fmt.Errorf("invalid issuer")
+	// ErrInvalidAudience aud 中没有预期的受众
+	ErrInvalidAudience = fmt.Errorf("invalid audience")
+	// ErrMissingClaim 缺少必需的声明
+	ErrMissingClaim = fmt.Errorf("missing claim")
+	// ErrRefreshToken refresh token 不能作为 access token 使用
+	ErrRefreshToken = fmt.Errorf("refresh token used as access token")
+	// ErrNotRefreshToken 不是 refresh token
+	ErrNotRefreshToken = fmt.Errorf("not a refresh token")
+	// ErrInvalidTokenType token 类型不是服务端接受的类型
+	ErrInvalidTokenType = fmt.Errorf("invalid token type")
+)
+
+// Claims token 中的标准声明及业务自定义数据
+type Claims struct {
+	Issuer    string      // iss 签发者
+	Subject   string      // sub 主体, 如用户 ID
+	Audience  []string    // aud 受众
+	ExpiresAt time.Time   // exp 过期时间
+	NotBefore time.Time   // nbf 生效时间
+	IssuedAt  time.Time   // iat 签发时间
+	ID        string      // jti token ID, 签发时为空则随机生成
+	Type      string      // token 类型, TokenTypeAccess, TokenTypeRefresh 或 TokenTypeService, 为空时视为 access token
+	Custom    interface{} // 业务自定义的数据
+}
+
+// ClaimsSigner 可以指定完整 claims 的签名器
+type ClaimsSigner interface {
+	// SignClaims 生成签名, 未设置的 iss, iat, exp 使用签名器的默认值
+	SignClaims(c *Claims) (string, error)
+}
+
+// PairSigner 可以签发 access token 及 refresh token 的签名器
+type PairSigner interface {
+	// SignPair 签发 access token 及有效期更长的 refresh token
+	SignPair(c *Claims) (access, refresh string, err error)
+	// VerifyRefresh 校验 refresh token, access token 会被拒绝
+	VerifyRefresh(token string) (*Claims, error)
+}
+
+// ClaimsVerifier 可以返回完整 claims 的签名器
+type ClaimsVerifier interface {
+	// VerifyClaims 校验 token, 成功则返回完整 claims
+	VerifyClaims(token string) (*Claims, error)
+}
+
+// verifyClaims 使用 signer 校验 token, signer 未实现 ClaimsVerifier 时只返回自定义数据
+func verifyClaims(signer Signer, token string) (*Claims, error) {
+	if v, ok := signer.(ClaimsVerifier); ok {
+		return v.VerifyClaims(token)
+	}
+	custom, err := signer.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return &Claims{Custom: custom}, nil
+}
+
+// claims 元数据
+type claims struct {
+	jwt.RegisteredClaims
+	// token 类型
+	Type string `json:"token_use,omitempty"`
+	// 业务自定义的数据
+	Custom interface{} `json:"custom,omitempty"`
+}
+
+func newClaims(c *Claims) *claims {
+	return &claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  c.Audience,
+			ExpiresAt: at(c.ExpiresAt),
+			ID:        c.ID,
+			IssuedAt:  at(c.IssuedAt),
+			Issuer:    c.Issuer,
+			NotBefore: at(c.NotBefore),
+			Subject:   c.Subject,
+		},
+		Type:   c.Type,
+		Custom: c.Custom,
+	}
+}
+
+func (c *claims) export() *Claims {
+	return &Claims{
+		Issuer:    c.Issuer,
+		Subject:   c.Subject,
+		Audience:  c.Audience,
+		ExpiresAt: timeOf(c.ExpiresAt),
+		NotBefore: timeOf(c.NotBefore),
+		IssuedAt:  timeOf(c.IssuedAt),
+		ID:        c.ID,
+		Type:      c.Type,
+		Custom:    c.Custom,
+	}
+}
+
+func at(t time.Time) *jwt.NumericDate {
+	if t.IsZero() {
+		return nil
+	}
+	return jwt.NewNumericDate(t)
+}
+
+func timeOf(t *jwt.NumericDate) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time
+}
+
+// has 是否包含声明 name, 非标准声明在自定义数据中查找
+func (c *Claims) has(name string) bool {
+	switch name {
+	case ClaimIssuer:
+		return c.Issuer != ""
+	case ClaimSubject:
+		return c.Subject != ""
+	case ClaimAudience:
+		return len(c.Audience) > 0
+	case ClaimExpiresAt:
+		return !c.ExpiresAt.IsZero()
+	case ClaimNotBefore:
+		return !c.NotBefore.IsZero()
+	case ClaimIssuedAt:
+		return !c.IssuedAt.IsZero()
+	case ClaimID:
+		return c.ID != ""
+	default:
+		custom, ok := c.Custom.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		_, ok = custom[name]
+		return ok
+	}
+}
+
+// validation 标准声明的校验规则
+type validation struct {
+	issuers   []string      // 预期的签发者, 为空时不校验
+	audiences []string      // 预期的受众, aud 包含其中之一即可, 为空时不校验
+	leeway    time.Duration // 校验 exp, nbf 时允许的时钟偏差
+	required  []string      // 必需的声明
+	types     []string      // 接受的 token 类型, 为空时只接受 access token
+}
+
+// validate 校验 iss, aud 及必需的声明, exp 和 nbf 在解析时校验
+func (v *validation) validate(c *Claims) error {
+	for _, name := range v.required {
+		if !c.has(name) {
+			return fmt.Errorf("%w: %s", ErrMissingClaim, name)
+		}
+	}
+	if len(v.issuers) > 0 && !contains(v.issuers, c.Issuer) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, c.Issuer)
+	}
+	if len(v.audiences) > 0 && !containsAny(v.audiences, c.Audience) {
+		return fmt.Errorf("%w: %q", ErrInvalidAudience, c.Audience)
+	}
+	return nil
+}
+
+// validateType 校验 access token 的类型, 未设置接受的类型时只接受 access token
+func (v *validation) validateType(c *Claims) error {
+	typ := c.Type
+	if typ == "" {
+		typ = TokenTypeAccess
+	}
+	if len(v.types) == 0 && typ == TokenTypeAccess || contains(v.types, typ) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidTokenType, typ)
+}
+
+func contains(set []string, s string) bool {
+	for _, v := range set {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(set []string, ss []string) bool {
+	for _, s := range ss {
+		if contains(set, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyClaims_Issuer(t *testing.T) {
+	secret := []byte("123456")
+	token, err := NewJwtSign(secret, time.Hour, "partner").Sign(mockUserInfo())
+	assert.Nil(t, err)
+
+	// The issuer is not validated by default.
+	_, err = NewJwtSign(secret, time.Hour, "issuer").Verify(token)
+	assert.Nil(t, err)
+	_, err = NewJwtSign(secret, time.Hour, "issuer", WithIssuers("issuer")).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidIssuer)
+	c, err := NewJwtSign(secret, time.Hour, "issuer", WithIssuers("issuer", "partner")).(ClaimsVerifier).
+		VerifyClaims(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "partner", c.Issuer)
+	_, err = NewJwtSign(secret, time.Hour, "").Verify(token)
+	assert.Nil(t, err)
+}
+
+func TestVerifyClaims_Audience(t *testing.T) {
+	sign := NewJwtSign([]byte("123456"), time.Hour, "issuer", WithAudiences("svc-a", "svc-b"))
+	token, err := sign.(ClaimsSigner).SignClaims(&Claims{Audience: []string{"svc-b", "svc-c"}, Subject: "user"})
+	assert.Nil(t, err)
+	c, err := sign.(ClaimsVerifier).VerifyClaims(token)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"svc-b", "svc-c"}, c.Audience)
+	assert.Equal(t, "user", c.Subject)
+	assert.Equal(t, "issuer", c.Issuer)
+	assert.False(t, c.IssuedAt.IsZero())
+
+	token, err = sign.(ClaimsSigner).SignClaims(&Claims{Audience: []string{"svc-c"}})
+	assert.Nil(t, err)
+	_, err = sign.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+	token, err = sign.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	_, err = sign.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+}
+
+func TestVerifyClaims_Leeway(t *testing.T) {
+	now := time.Now()
+	sign := NewJwtSign([]byte("123456"), time.Hour, "issuer")
+	notYet, err := sign.(ClaimsSigner).SignClaims(&Claims{NotBefore: now.Add(2 * time.Second)})
+	assert.Nil(t, err)
+	expired, err := sign.(ClaimsSigner).SignClaims(&Claims{ExpiresAt: now.Add(-2 * time.Second)})
+	assert.Nil(t, err)
+	_, err = sign.Verify(notYet)
+	assert.NotNil(t, err)
+	_, err = sign.Verify(expired)
+	assert.NotNil(t, err)
+
+	sign = NewJwtSign([]byte("123456"), time.Hour, "issuer", WithLeeway(5*time.Second))
+	_, err = sign.Verify(notYet)
+	assert.Nil(t, err)
+	_, err = sign.Verify(expired)
+	assert.Nil(t, err)
+}
+
+func TestVerifyClaims_Required(t *testing.T) {
+	sign := NewJwtSign([]byte("123456"), time.Hour, "issuer",
+		WithRequiredClaims(ClaimSubject, ClaimID, "name"))
+	token, err := sign.(ClaimsSigner).SignClaims(&Claims{Subject: "user", ID: "1",
+		Custom: map[string]interface{}{"name": "forrestsun"}})
+	assert.Nil(t, err)
+	_, err = sign.Verify(token)
+	assert.Nil(t, err)
+
+	token, err = sign.(ClaimsSigner).SignClaims(&Claims{Subject: "user", ID: "1"})
+	assert.Nil(t, err)
+	_, err = sign.Verify(token)
+	assert.ErrorIs(t, err, ErrMissingClaim)
+	token, err = sign.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	_, err = sign.Verify(token)
+	assert.ErrorIs(t, err, ErrMissingClaim)
+}
@@ -18,9 +18,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/mitchellh/mapstructure"
 
+	"trpc.group/trpc-go/trpc-go"
 	"trpc.group/trpc-go/trpc-go/errs"
 	"trpc.group/trpc-go/trpc-go/filter"
 	trpcHttp "trpc.group/trpc-go/trpc-go/http"
@@ -32,6 +34,8 @@ type ContextKey string
 const (
 	// AuthJwtCtxKey context key
 	AuthJwtCtxKey = ContextKey("AuthJwtCtxKey")
+	// AuthClaimsCtxKey 完整 claims 的 context key
+	AuthClaimsCtxKey = ContextKey("AuthClaimsCtxKey")
 )
 
 // DefaultSigner 默认的 signer
@@ -44,6 +48,17 @@ func SetDefaultSigner(s Signer) {
 	}
 }
 
+// signers 插件实例名 -> 签名器
+var signers sync.Map
+
+// GetSigner 获取插件实例 name 的签名器, name 为插件名 jwt 或 instances 中的实例名, 不存在时返回 nil
+func GetSigner(name string) Signer {
+	if s, ok := signers.Load(name); ok {
+		return s.(Signer)
+	}
+	return nil
+}
+
 // DefaultParseTokenFunc 默认获取 token 的函数, 用户可自定义实现
 var DefaultParseTokenFunc = func(ctx context.Context, req interface{}) (string, error) {
 	head := trpcHttp.Head(ctx)
@@ -53,13 +68,41 @@ var DefaultParseTokenFunc = func(ctx context.Context, req interface{}) (string,
 
 // options 插件配置
 type options struct {
-	ExcludePathSet map[string]bool // path 白名单
+	ExcludePathSet map[string]bool // path 白名单, 支持 RPC 名及通配符
+
+	tokenSources        []TokenSource            // token 来源, 为空时使用 DefaultParseTokenFunc
+	serviceTokenSources map[string][]TokenSource // 按服务名配置的 token 来源
+	revocationStore     RevocationStore          // 吊销存储, 为空时使用 DefaultRevocationStore
+	authorization       *Authorization           // 方法级鉴权策略
+	signer              Signer                   // 签名器, 为空时使用 DefaultSigner
 }
 
-// isInExcludePath 是否在白名单列表中
-func (o *options) isInExcludePath(path string) bool {
-	_, ok := o.ExcludePathSet[path]
-	return ok
+// sources 返回服务的 token 来源
+func (o *options) sources(service string) []TokenSource {
+	if sources, ok := o.serviceTokenSources[service]; ok {
+		return sources
+	}
+	return o.tokenSources
+}
+
+// isInExcludePath paths 之一是否在白名单列表中, 白名单可以是通配符
+func (o *options) isInExcludePath(paths ...string) bool {
+	for _, path := range paths {
+		if _, ok := o.ExcludePathSet[path]; ok {
+			return true
+		}
+	}
+	for pattern := range o.ExcludePathSet {
+		if !isGlob(pattern) {
+			continue
+		}
+		for _, path := range paths {
+			if globMatch(pattern, path) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Option 设置参数选项
@@ -72,6 +115,42 @@ func WithExcludePathSet(set map[string]bool) Option {
 	}
 }
 
+// WithTokenSources 设置 token 来源, 按顺序取第一个非空的 token。
+// 来源包含 metadata 时, 非 HTTP 请求也需要校验 token, 否则跳过非 HTTP 请求。
+func WithTokenSources(sources ...TokenSource) Option {
+	return func(o *options) {
+		o.tokenSources = sources
+	}
+}
+
+// WithServiceTokenSources 按服务名设置 token 来源, 未配置的服务使用 WithTokenSources 的来源
+func WithServiceTokenSources(sources map[string][]TokenSource) Option {
+	return func(o *options) {
+		o.serviceTokenSources = sources
+	}
+}
+
+// WithRevocationStore 设置吊销存储, 未设置时使用 DefaultRevocationStore
+func WithRevocationStore(store RevocationStore) Option {
+	return func(o *options) {
+		o.revocationStore = store
+	}
+}
+
+// WithSigner 设置校验 token 的签名器, 未设置时使用 DefaultSigner
+func WithSigner(s Signer) Option {
+	return func(o *options) {
+		o.signer = s
+	}
+}
+
+// WithAuthorization 设置方法级鉴权策略, token 校验通过后按 RPC 名或 HTTP path 匹配策略并校验 claims
+func WithAuthorization(a *Authorization) Option {
+	return func(o *options) {
+		o.authorization = a
+	}
+}
+
 // ServerFilter 设置服务端增加 jwt 验证
 func ServerFilter(opts ...Option) filter.ServerFilter {
 	o := &options{}
@@ -79,30 +158,66 @@ func ServerFilter(opts ...Option) filter.ServerFilter {
 		opt(o)
 	}
 	return func(ctx context.Context, req interface{}, handler filter.ServerHandleFunc) (interface{}, error) {
-		var head = trpcHttp.Head(ctx)
-		// 非http请求
-		if head == nil {
+		var (
+			msg     = trpc.Message(ctx)
+			head    = trpcHttp.Head(ctx)
+			sources = o.sources(msg.CalleeServiceName())
+			rpcName = msg.ServerRPCName()
+			path    = rpcName
+		)
+		// 非http请求, 且没有配置 metadata 来源
+		if head == nil && !hasMetadataSource(sources) {
 			return handler(ctx, req)
 		}
+		if head != nil && head.Request.URL != nil {
+			path = head.Request.URL.Path
+		}
 		// 是否跳过OA验证(path白名单)
-		var r = head.Request
-		if r.URL != nil && o.isInExcludePath(r.URL.Path) {
+		if o.isInExcludePath(path, rpcName) {
 			return handler(ctx, req)
 		}
-		token, err := DefaultParseTokenFunc(ctx, req)
+		token, err := parseToken(ctx, req, sources)
 		if err != nil {
 			return nil, err
 		}
-		customInfo, err := DefaultSigner.Verify(token)
+		signer := o.signer
+		if signer == nil {
+			signer = DefaultSigner
+		}
+		claims, err := verifyClaims(signer, token)
 		if err != nil {
 			return nil, errs.NewFrameError(errs.RetServerAuthFail, err.Error())
 		}
+		store := o.revocationStore
+		if store == nil {
+			store = DefaultRevocationStore
+		}
+		if store != nil {
+			if err := checkRevoked(ctx, store, claims); err != nil {
+				return nil, errs.NewFrameError(errs.RetServerAuthFail, err.Error())
+			}
+		}
+		if o.authorization != nil {
+			if err := o.authorization.authorize(claims, path, rpcName); err != nil {
+				return nil, errs.NewFrameError(errs.RetServerAuthFail, err.Error())
+			}
+		}
 		// 将认证信息存储到ctx中用于业务侧使用
-		innerCtx := context.WithValue(ctx, AuthJwtCtxKey, customInfo)
+		innerCtx := context.WithValue(ctx, AuthJwtCtxKey, claims.Custom)
+		innerCtx = context.WithValue(innerCtx, AuthClaimsCtxKey, claims)
+		innerCtx = context.WithValue(innerCtx, AuthTokenCtxKey, token)
 		return handler(innerCtx, req)
 	}
 }
 
+// parseToken 从请求中获取 token, 未配置来源时使用 DefaultParseTokenFunc
+func parseToken(ctx context.Context, req interface{}, sources []TokenSource) (string, error) {
+	if len(sources) == 0 {
+		return DefaultParseTokenFunc(ctx, req)
+	}
+	return ExtractToken(ctx, sources...), nil
+}
+
 // GetCustomInfo 获取用户信息 (参数 ptr 为struct的指针对象)
 func GetCustomInfo(ctx context.Context, ptr interface{}) error {
 	if data, ok := ctx.Value(AuthJwtCtxKey).(map[string]interface{}); ok {
@@ -110,3 +225,9 @@ func GetCustomInfo(ctx context.Context, ptr interface{}) error {
 	}
 	return fmt.Errorf("fail to find ctx value! key=(%v)", AuthJwtCtxKey)
 }
+
+// GetClaims 获取完整 claims, 包括 iss, sub, aud 等标准声明及自定义数据
+func GetClaims(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(AuthClaimsCtxKey).(*Claims)
+	return c, ok
+}
@@ -15,6 +15,9 @@ package jwt
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"strings"
 	"time"
 
 	"trpc.group/trpc-go/trpc-go/filter"
@@ -43,7 +46,96 @@ type Config struct {
 	Secret       string   `yaml:"secret"`        // 签名使用的私钥
 	Expired      int      `yaml:"expired"`       // 过期时间 seconds
 	Issuer       string   `yaml:"issuer"`        // 发行人
-	ExcludePaths []string `yaml:"exclude_paths"` // 跳过 jwt 鉴权的 paths, 如登陆接口
+	ExcludePaths []string `yaml:"exclude_paths"` // 跳过 jwt 鉴权的 paths 或 RPC 名, 如登陆接口, 支持通配符
+
+	Algorithm  string            `yaml:"algorithm"`   // 签名算法, 默认 HS512, 非对称算法如 RS256, ES256, EdDSA
+	KeyID      string            `yaml:"key_id"`      // 签名密钥的 kid
+	PrivateKey string            `yaml:"private_key"` // 非对称算法签名私钥的 PEM 文件路径
+	PublicKeys map[string]string `yaml:"public_keys"` // 验签公钥, kid -> PEM 文件路径
+	JWKS       *JWKSConfig       `yaml:"jwks"`        // 从 JWKS 加载验签公钥
+
+	Issuers        []string `yaml:"issuers"`         // 预期的签发者, 为空时不校验 iss
+	Audiences      []string `yaml:"audiences"`       // 预期的受众, aud 包含其中之一即可, 为空时不校验
+	Leeway         int      `yaml:"leeway"`          // 校验 exp, nbf 时允许的时钟偏差 seconds
+	RequiredClaims []string `yaml:"required_claims"` // 必需的声明, 如 sub, aud, jti
+	TokenTypes     []string `yaml:"token_types"`     // 接受的 token 类型, 默认只接受 access, 接受服务间 token 时需包含 service
+
+	TokenSources        []TokenSource            `yaml:"token_sources"`         // token 来源, 默认 HTTP Authorization 头部
+	ServiceTokenSources map[string][]TokenSource `yaml:"service_token_sources"` // 按服务名配置的 token 来源
+	ClientToken         *TokenSource             `yaml:"client_token"`          // 客户端写入 token 的位置, 默认 metadata authorization
+	PropagateCallees    []string                 `yaml:"propagate_callees"`     // 客户端透传服务端收到的 token 的被调服务, 支持通配符, 为空时不透传
+	Mint                *MintConfig              `yaml:"mint"`                  // 客户端签发服务间 token, 配置后不再透传
+
+	RefreshExpired int               `yaml:"refresh_expired"` // refresh token 过期时间 seconds, 默认 7 天
+	Revocation     *RevocationConfig `yaml:"revocation"`      // 内存吊销存储的容量及保留时长, 未配置时使用默认值
+
+	Authorization `yaml:",inline"` // 方法级鉴权策略
+
+	Instances map[string]*Config `yaml:"instances"` // 其他插件实例, 实例名 -> 配置, 每个实例注册同名的拦截器
+}
+
+// RevocationConfig 内存吊销存储配置
+type RevocationConfig struct {
+	Capacity int `yaml:"capacity"` // 内存吊销存储的容量, 默认 100000
+	TTL      int `yaml:"ttl"`      // 没有过期时间的 token 的吊销记录保留时长 seconds, 默认 86400
+}
+
+// MintConfig 客户端签发服务间 token 的配置
+type MintConfig struct {
+	TTL           int    `yaml:"ttl"`            // token 有效期 seconds, 默认 300
+	Identity      string `yaml:"identity"`       // 主调服务身份, 默认为主调服务名
+	PropagateUser bool   `yaml:"propagate_user"` // 是否透传服务端校验通过的终端用户信息
+}
+
+// options 转换为签发选项
+func (c *MintConfig) options() []MintOption {
+	opts := []MintOption{WithIdentity(c.Identity), WithPropagateUser(c.PropagateUser)}
+	if c.TTL > 0 {
+		opts = append(opts, WithMintTTL(time.Duration(c.TTL)*time.Second))
+	}
+	return opts
+}
+
+// validate 校验 token 来源及鉴权策略配置
+func (c *Config) validate() error {
+	for i, p := range c.Policies {
+		if len(p.Match) == 0 {
+			return fmt.Errorf("JWT policy %d: match is empty", i)
+		}
+		for _, cond := range p.Claims {
+			if cond.Claim == "" || (cond.Equals == "" && cond.Contains == "") {
+				return fmt.Errorf("JWT policy %d: claim condition requires claim and equals or contains", i)
+			}
+		}
+	}
+	for _, s := range c.TokenSources {
+		if err := s.validate(false); err != nil {
+			return err
+		}
+	}
+	for _, sources := range c.ServiceTokenSources {
+		for _, s := range sources {
+			if err := s.validate(false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, callee := range c.PropagateCallees {
+		if _, err := path.Match(callee, ""); err != nil {
+			return fmt.Errorf("JWT propagate callee %q: %w", callee, err)
+		}
+	}
+	if c.ClientToken != nil {
+		return c.ClientToken.validate(true)
+	}
+	return nil
+}
+
+// JWKSConfig JWKS 配置
+type JWKSConfig struct {
+	URL     string `yaml:"url"`     // JWKS 文件路径或 http(s) URL
+	Refresh int    `yaml:"refresh"` // 刷新间隔 seconds, 默认 300, 小于 0 时不刷新
+	Grace   int    `yaml:"grace"`   // 轮换后旧密钥的宽限期 seconds, 默认 3600
 }
 
 // Setup 插件实例初始化
@@ -53,20 +145,169 @@ func (p *pluginImp) Setup(name string, configDec plugin.Decoder) error {
 	if err := configDec.Decode(&cfg); err != nil {
 		return err
 	}
-	if cfg.Secret == "" {
-		return fmt.Errorf("JWT secret not be empty")
+	for instance, c := range cfg.Instances {
+		if instance == name || c == nil || len(c.Instances) > 0 {
+			return fmt.Errorf("JWT instance %s: invalid name or config", instance)
+		}
 	}
-	// 未设置-默认为1小时过期
-	var expired = time.Hour
-	if cfg.Expired > 0 {
-		expired = time.Duration(cfg.Expired) * time.Second
+	// 默认实例的服务端拦截器使用 DefaultSigner, 可以通过 SetDefaultSigner 覆盖
+	signer, err := setup(name, &cfg, false)
+	if err != nil {
+		return err
+	}
+	SetDefaultSigner(signer)
+	for instance, c := range cfg.Instances {
+		// 其他实例的服务端拦截器使用各自的签名器
+		if _, err := setup(instance, c, true); err != nil {
+			return fmt.Errorf("JWT instance %s: %w", instance, err)
+		}
 	}
+	return nil
+}
+
+// setup 构造实例 name 的签名器, 注册签名器及同名的拦截器, bind 为 true 时服务端拦截器使用该签名器, 否则使用 DefaultSigner
+func setup(name string, cfg *Config, bind bool) (Signer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	signer, err := newSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// 用户已通过 SetDefaultRevocationStore 设置 Redis 等共享存储时, 不覆盖; 各实例共享吊销存储
+	if cfg.Revocation != nil && DefaultRevocationStore == builtinRevocationStore {
+		SetDefaultRevocationStore(NewMemoryRevocationStore(cfg.Revocation.Capacity,
+			WithRevocationTTL(time.Duration(cfg.Revocation.TTL)*time.Second)))
+	}
+	var opts []Option
+	if bind {
+		opts = append(opts, WithSigner(signer))
+	}
+	signers.Store(name, signer)
+	filter.Register(name, newServerFilter(cfg, opts...), newClientFilter(cfg, signer))
+	return signer, nil
+}
+
+// newServerFilter 根据配置构造服务端拦截器
+func newServerFilter(cfg *Config, opts ...Option) filter.ServerFilter {
 	// slice 转换为 map
 	var excludePathSet = make(map[string]bool)
 	for _, s := range cfg.ExcludePaths {
 		excludePathSet[s] = true
 	}
-	SetDefaultSigner(NewJwtSign([]byte(cfg.Secret), expired, cfg.Issuer))
-	filter.Register(name, ServerFilter(WithExcludePathSet(excludePathSet)), nil)
-	return nil
+	return ServerFilter(append([]Option{
+		WithExcludePathSet(excludePathSet),
+		WithTokenSources(cfg.TokenSources...),
+		WithServiceTokenSources(cfg.ServiceTokenSources),
+		WithAuthorization(&cfg.Authorization),
+	}, opts...)...)
+}
+
+// newClientFilter 根据配置构造客户端拦截器, 未配置 mint 及 propagate_callees 时不写入 token, 返回 nil
+func newClientFilter(cfg *Config, signer Signer) filter.ClientFilter {
+	var clientOpts []ClientOption
+	if cfg.ClientToken != nil {
+		clientOpts = append(clientOpts, WithTokenTarget(*cfg.ClientToken))
+	}
+	switch {
+	case cfg.Mint != nil:
+		clientOpts = append(clientOpts, WithMinting(signer, cfg.Mint.options()...))
+	case len(cfg.PropagateCallees) > 0:
+		clientOpts = append(clientOpts, WithCallees(cfg.PropagateCallees...))
+	default:
+		return nil
+	}
+	return ClientFilter(clientOpts...)
+}
+
+// newSigner 根据配置构造签名器
+func newSigner(cfg *Config) (Signer, error) {
+	if cfg.Secret == "" && cfg.PrivateKey == "" && len(cfg.PublicKeys) == 0 && cfg.JWKS == nil {
+		return nil, fmt.Errorf("JWT secret not be empty")
+	}
+	// 未设置-默认为1小时过期
+	var expired = time.Hour
+	if cfg.Expired > 0 {
+		expired = time.Duration(cfg.Expired) * time.Second
+	}
+	var opts []SignOption
+	switch {
+	case cfg.PrivateKey != "":
+		data, err := os.ReadFile(cfg.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT private key: %w", err)
+		}
+		priv, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		alg := cfg.Algorithm
+		if alg == "" {
+			alg = AlgorithmOf(priv)
+		}
+		opts = append(opts, WithSigningKey(&Key{ID: cfg.KeyID, Algorithm: alg, Key: priv}))
+	case cfg.Secret != "" && (cfg.Algorithm != "" || cfg.KeyID != ""):
+		alg := cfg.Algorithm
+		if alg == "" {
+			alg = defaultAlgorithm
+		}
+		if !strings.HasPrefix(alg, "HS") {
+			return nil, fmt.Errorf("JWT private_key is required by algorithm %s", alg)
+		}
+		opts = append(opts, WithSigningKey(&Key{ID: cfg.KeyID, Algorithm: alg, Key: []byte(cfg.Secret)}))
+	}
+	keys, err := newKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		WithKeySet(keys),
+		WithAudiences(cfg.Audiences...),
+		WithLeeway(time.Duration(cfg.Leeway)*time.Second),
+		WithRequiredClaims(cfg.RequiredClaims...),
+		WithTokenTypes(cfg.TokenTypes...))
+	if cfg.RefreshExpired > 0 {
+		opts = append(opts, WithRefreshExpired(time.Duration(cfg.RefreshExpired)*time.Second))
+	}
+	if len(cfg.Issuers) > 0 {
+		opts = append(opts, WithIssuers(cfg.Issuers...))
+	}
+	return NewJwtSign([]byte(cfg.Secret), expired, cfg.Issuer, opts...), nil
+}
+
+// newKeySet 根据配置加载验签公钥
+func newKeySet(cfg *Config) (*KeySet, error) {
+	var keys *KeySet
+	if cfg.JWKS != nil {
+		refresh, grace := 300*time.Second, time.Hour
+		if cfg.JWKS.Refresh != 0 {
+			refresh = time.Duration(cfg.JWKS.Refresh) * time.Second
+		}
+		if cfg.JWKS.Grace > 0 {
+			grace = time.Duration(cfg.JWKS.Grace) * time.Second
+		}
+		var err error
+		if keys, err = NewJWKSKeySet(cfg.JWKS.URL, refresh, grace); err != nil {
+			return nil, err
+		}
+	} else {
+		keys = NewKeySet(0)
+	}
+	for kid, file := range cfg.PublicKeys {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT public key: %w", err)
+		}
+		pub, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		// 未配置算法时按公钥类型推断, 混用不同类型的公钥时不要配置 algorithm
+		alg := cfg.Algorithm
+		if alg == "" {
+			alg = AlgorithmOf(pub)
+		}
+		keys.Add(&Key{ID: kid, Algorithm: alg, Key: pub})
+	}
+	return keys, nil
 }
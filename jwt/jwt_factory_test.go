@@ -14,9 +14,18 @@
 package jwt
 
 import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/filter"
 
 	"github.com/stretchr/testify/assert"
 	yaml "gopkg.in/yaml.v3"
@@ -55,3 +64,253 @@ func TestPlugin_Setup(t *testing.T) {
 	err = p.Setup(pluginName, &JWTCfg)
 	assert.Nil(t, err)
 }
+
+// TestPlugin_SetupKeys 非对称签名及 JWKS 配置单元测试
+func TestPlugin_SetupKeys(t *testing.T) {
+	dir := t.TempDir()
+	keys := mockPrivateKeys(t)
+	der, err := x509.MarshalPKCS8PrivateKey(keys["ES256"])
+	assert.Nil(t, err)
+	privFile := filepath.Join(dir, "private.pem")
+	assert.Nil(t, os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	der, err = x509.MarshalPKIXPublicKey(keys["RS256"].Public())
+	assert.Nil(t, err)
+	pubFile := filepath.Join(dir, "public.pem")
+	assert.Nil(t, os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	jwks, err := MarshalJWKS(&Key{ID: "jwks", Algorithm: AlgEdDSA, Key: keys[AlgEdDSA]})
+	assert.Nil(t, err)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	assert.Nil(t, os.WriteFile(jwksFile, jwks, 0600))
+
+	signer, err := newSigner(&Config{
+		Issuer:     "tencent",
+		KeyID:      "current",
+		PrivateKey: privFile,
+		PublicKeys: map[string]string{"partner": pubFile},
+		JWKS:       &JWKSConfig{URL: jwksFile, Refresh: -1},
+	})
+	assert.Nil(t, err)
+	for kid, key := range map[string]*Key{
+		"current": {ID: "current", Algorithm: "ES256", Key: keys["ES256"]},
+		"partner": {ID: "partner", Algorithm: "RS256", Key: keys["RS256"]},
+		"jwks":    {ID: "jwks", Algorithm: AlgEdDSA, Key: keys[AlgEdDSA]},
+	} {
+		token, err := NewJwtSign(nil, time.Hour, "tencent", WithSigningKey(key)).Sign(mockUserInfo())
+		assert.Nil(t, err, kid)
+		_, err = signer.Verify(token)
+		assert.Nil(t, err, kid)
+	}
+
+	_, err = newSigner(&Config{})
+	assert.NotNil(t, err)
+	_, err = newSigner(&Config{Secret: "secret", Algorithm: "RS256"})
+	assert.NotNil(t, err)
+	_, err = newSigner(&Config{PrivateKey: filepath.Join(dir, "not_exist.pem")})
+	assert.NotNil(t, err)
+}
+
+// TestPlugin_SetupClaims 标准声明校验配置单元测试
+func TestPlugin_SetupClaims(t *testing.T) {
+	cfg := Config{}
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+secret: q7wt3n1t
+issuer: tencent
+issuers: [tencent, partner]
+audiences: [svc]
+leeway: 30
+required_claims: [sub]
+`), &cfg))
+	signer, err := newSigner(&cfg)
+	assert.Nil(t, err)
+	token, err := signer.(ClaimsSigner).SignClaims(&Claims{Issuer: "partner", Subject: "user", Audience: []string{"svc"},
+		NotBefore: time.Now().Add(10 * time.Second)})
+	assert.Nil(t, err)
+	_, err = signer.Verify(token)
+	assert.Nil(t, err)
+	token, err = signer.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	_, err = signer.Verify(token)
+	assert.NotNil(t, err)
+}
+
+// TestPlugin_SetupTokenSources token 来源配置单元测试
+func TestPlugin_SetupTokenSources(t *testing.T) {
+	setup := func(conf string) error {
+		var node yaml.Node
+		assert.Nil(t, yaml.Unmarshal([]byte(conf), &node))
+		return (&pluginImp{}).Setup(pluginName, &node)
+	}
+	assert.Nil(t, setup(`
+secret: q7wt3n1t
+token_sources:
+  - type: metadata
+    name: authorization
+  - type: header
+    name: Authorization
+    prefix: "Bearer "
+service_token_sources:
+  trpc.app.server.Greeter:
+    - type: cookie
+      name: token
+client_token:
+  type: metadata
+  name: authorization
+`))
+	assert.NotNil(t, setup(`
+secret: q7wt3n1t
+token_sources:
+  - type: body
+    name: token
+`))
+	assert.NotNil(t, setup(`
+secret: q7wt3n1t
+client_token:
+  type: query
+  name: token
+`))
+	assert.NotNil(t, setup(`
+secret: q7wt3n1t
+propagate_callees: ["trpc.app.[server"]
+`))
+}
+
+// TestPlugin_SetupClientFilter 客户端拦截器配置单元测试
+func TestPlugin_SetupClientFilter(t *testing.T) {
+	setup := func(conf string) {
+		var node yaml.Node
+		assert.Nil(t, yaml.Unmarshal([]byte(conf), &node))
+		assert.Nil(t, (&pluginImp{}).Setup(pluginName, &node))
+	}
+	// 默认不透传服务端收到的 token
+	setup(`
+secret: q7wt3n1t
+`)
+	assert.Nil(t, filter.GetClient(pluginName))
+
+	setup(`
+secret: q7wt3n1t
+propagate_callees: [trpc.app.trusted.*]
+`)
+	f := filter.GetClient(pluginName)
+	assert.NotNil(t, f)
+	handler := func(ctx context.Context, req, rsp interface{}) error { return nil }
+	for callee, want := range map[string]string{"trpc.app.trusted.Greeter": "token", "trpc.app.other.Greeter": ""} {
+		ctx := context.WithValue(trpc.BackgroundContext(), AuthTokenCtxKey, "token")
+		trpc.Message(ctx).WithCalleeServiceName(callee)
+		assert.Nil(t, f(ctx, nil, nil, handler))
+		assert.Equal(t, want, string(trpc.Message(ctx).ClientMetaData()["authorization"]), callee)
+	}
+}
+
+// TestPlugin_SetupRevocation 吊销及 refresh token 配置单元测试
+func TestPlugin_SetupRevocation(t *testing.T) {
+	var node yaml.Node
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+secret: q7wt3n1t
+refresh_expired: 3600
+revocation:
+  capacity: 10
+  ttl: 60
+`), &node))
+	defer func() { DefaultRevocationStore = builtinRevocationStore }()
+	assert.Nil(t, (&pluginImp{}).Setup(pluginName, &node))
+	store := DefaultRevocationStore.(*memoryRevocationStore)
+	assert.Equal(t, 10, store.capacity)
+	assert.Equal(t, time.Minute, store.ttl)
+	// 已设置的共享存储不被覆盖
+	shared := NewMemoryRevocationStore(0)
+	DefaultRevocationStore = shared
+	assert.Nil(t, (&pluginImp{}).Setup(pluginName, &node))
+	assert.Equal(t, shared, DefaultRevocationStore)
+	_, refresh, err := DefaultSigner.(PairSigner).SignPair(&Claims{})
+	assert.Nil(t, err)
+	c, err := DefaultSigner.(PairSigner).VerifyRefresh(refresh)
+	assert.Nil(t, err)
+	assert.True(t, c.ExpiresAt.Before(time.Now().Add(2*time.Hour)))
+}
+
+// TestPlugin_SetupAuthorization 鉴权策略配置单元测试
+func TestPlugin_SetupAuthorization(t *testing.T) {
+	setup := func(conf string) error {
+		var node yaml.Node
+		assert.Nil(t, yaml.Unmarshal([]byte(conf), &node))
+		return (&pluginImp{}).Setup(pluginName, &node)
+	}
+	assert.Nil(t, setup(`
+secret: q7wt3n1t
+scope_claim: scp
+policies:
+  - match: ["/trpc.app.server.Admin/*"]
+    require_roles: [admin]
+  - match: ["/v1/orders/*"]
+    require_scopes: [orders:read]
+    claims:
+      - claim: tenant
+        equals: t1
+`))
+	assert.NotNil(t, setup(`
+secret: q7wt3n1t
+policies:
+  - require_roles: [admin]
+`))
+	assert.NotNil(t, setup(`
+secret: q7wt3n1t
+policies:
+  - match: ["*"]
+    claims:
+      - claim: tenant
+`))
+}
+
+// TestPlugin_SetupInstances 多实例配置单元测试
+func TestPlugin_SetupInstances(t *testing.T) {
+	var node yaml.Node
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+secret: q7wt3n1t
+issuer: internal
+instances:
+  jwt-partner:
+    secret: p4rtn3r
+    issuer: partner
+    token_sources:
+      - type: metadata
+        name: authorization
+`), &node))
+	assert.Nil(t, (&pluginImp{}).Setup(pluginName, &node))
+	internal, partner := GetSigner(pluginName), GetSigner("jwt-partner")
+	assert.NotNil(t, internal)
+	assert.NotNil(t, partner)
+	assert.Equal(t, internal, DefaultSigner)
+	assert.Nil(t, GetSigner("unknown"))
+
+	internalToken, err := internal.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	partnerToken, err := partner.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	f := filter.GetServer("jwt-partner")
+	assert.NotNil(t, f)
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithServerRPCName("/trpc.app.server.Partner/Hello")
+	msg.WithServerMetaData(codec.MetaData{"authorization": []byte(partnerToken)})
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+	msg.WithServerMetaData(codec.MetaData{"authorization": []byte(internalToken)})
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Equal(t, errs.RetServerAuthFail, errs.Code(err))
+
+	for _, conf := range []string{`
+secret: q7wt3n1t
+instances:
+  jwt:
+    secret: p4rtn3r
+`, `
+secret: q7wt3n1t
+instances:
+  jwt-partner:
+    issuer: partner
+`} {
+		assert.Nil(t, yaml.Unmarshal([]byte(conf), &node))
+		assert.NotNil(t, (&pluginImp{}).Setup(pluginName, &node))
+	}
+}
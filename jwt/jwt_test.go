@@ -48,3 +48,17 @@ func TestServerFilter(t *testing.T) {
 	_, err := f(ctx, []byte("req"), fakeServerHandleFunc)
 	assert.Nil(t, err)
 }
+
+func TestServerFilter_GetClaims(t *testing.T) {
+	f := ServerFilter()
+	m := &thttp.Header{Request: mockHttpRequest(), Response: &httptest.ResponseRecorder{}}
+	ctx := thttp.WithHeader(context.Background(), m)
+	_, err := f(ctx, []byte("req"), func(ctx context.Context, req interface{}) (interface{}, error) {
+		c, ok := GetClaims(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "issuer", c.Issuer)
+		assert.NotNil(t, c.Custom)
+		return fakeServerHandleFunc(ctx, req)
+	})
+	assert.Nil(t, err)
+}
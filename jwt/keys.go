@@ -0,0 +1,423 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// 默认的签名算法
+const defaultAlgorithm = "HS512"
+
+// AlgEdDSA Ed25519 签名算法
+const AlgEdDSA = "EdDSA"
+
+var (
+	// ErrUnknownKey 找不到 token 对应的验签密钥
+	ErrUnknownKey = fmt.Errorf("unknown key")
+	// ErrNoSigningKey 签名器未配置签名密钥
+	ErrNoSigningKey = fmt.Errorf("no signing key")
+)
+
+// Key 签名或验签密钥
+type Key struct {
+	ID        string      // kid, 为空时 token 头部不携带 kid
+	Algorithm string      // 签名算法, 如 HS512, RS256, ES256, EdDSA
+	Key       interface{} // HMAC 为 []byte, 非对称算法签名时为私钥, 验签时为公钥或私钥
+}
+
+// verifyKey 返回验签使用的密钥, 私钥转换为公钥
+func (k *Key) verifyKey() interface{} {
+	switch key := k.Key.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	case ed25519.PrivateKey:
+		return key.Public()
+	default:
+		return k.Key
+	}
+}
+
+// AlgorithmOf 返回密钥默认的签名算法
+func AlgorithmOf(key interface{}) string {
+	if s, ok := key.(crypto.Signer); ok {
+		key = s.Public()
+	}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return "RS256"
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P384():
+			return "ES384"
+		case elliptic.P521():
+			return "ES512"
+		default:
+			return "ES256"
+		}
+	case ed25519.PublicKey:
+		return AlgEdDSA
+	default:
+		return defaultAlgorithm
+	}
+}
+
+// ParsePrivateKeyPEM 解析 PEM 格式的私钥, 支持 PKCS1, PKCS8 和 SEC1
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse PEM private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM 解析 PEM 格式的公钥, 支持 PKIX, PKCS1 和证书
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM public key")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse PEM public key: %w", err)
+	}
+	return cert.PublicKey, nil
+}
+
+// keyEntry 密钥集合中的密钥
+type keyEntry struct {
+	key      *Key
+	static   bool      // 通过 Add 添加的密钥, 不会被 Update 淘汰
+	retireAt time.Time // 不为零时, 密钥在该时间后失效
+}
+
+// KeySet 验签密钥集合, 按 kid 查找, 并发安全。
+// 密钥轮换时, 被移除的密钥在宽限期内仍可验签, 以便验证轮换前签发的 token。
+type KeySet struct {
+	grace time.Duration
+	mu    sync.RWMutex
+	keys  map[string]*keyEntry
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// NewKeySet 构造密钥集合, grace 为轮换后旧密钥的宽限期
+func NewKeySet(grace time.Duration, keys ...*Key) *KeySet {
+	s := &KeySet{
+		grace: grace,
+		keys:  make(map[string]*keyEntry),
+		stop:  make(chan struct{}),
+	}
+	s.Add(keys...)
+	return s
+}
+
+// Add 添加固定密钥, 固定密钥不会被轮换淘汰
+func (s *KeySet) Add(keys ...*Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range keys {
+		s.keys[k.ID] = &keyEntry{key: k, static: true}
+	}
+}
+
+// Update 以 keys 替换轮换密钥, 不在 keys 中的旧密钥在宽限期后失效
+func (s *KeySet) Update(keys []*Key) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latest := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		latest[k.ID] = true
+		if e, ok := s.keys[k.ID]; ok && e.static {
+			continue
+		}
+		s.keys[k.ID] = &keyEntry{key: k}
+	}
+	for id, e := range s.keys {
+		if e.static || latest[id] {
+			continue
+		}
+		if e.retireAt.IsZero() {
+			e.retireAt = now.Add(s.grace)
+		}
+		if !now.Before(e.retireAt) {
+			delete(s.keys, id)
+		}
+	}
+}
+
+// Lookup 按 kid 查找密钥
+func (s *KeySet) Lookup(kid string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.keys[kid]
+	if !ok || (!e.retireAt.IsZero() && !time.Now().Before(e.retireAt)) {
+		return nil, false
+	}
+	return e.key, true
+}
+
+// Close 停止定时刷新
+func (s *KeySet) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *KeySet) closed() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewJWKSKeySet 从 JWKS 文档加载密钥集合, source 为文件路径或 http(s) URL。
+// refresh 大于 0 时定时刷新, 轮换掉的密钥在 grace 宽限期内仍可验签。
+func NewJWKSKeySet(source string, refresh, grace time.Duration) (*KeySet, error) {
+	keys, err := LoadJWKS(source)
+	if err != nil {
+		return nil, err
+	}
+	s := NewKeySet(grace)
+	s.Update(keys)
+	if refresh > 0 {
+		go s.refresh(source, refresh)
+	}
+	return s, nil
+}
+
+// refresh 定时刷新 JWKS, 加载失败时保留当前密钥
+func (s *KeySet) refresh(source string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if s.closed() {
+				return
+			}
+			keys, err := LoadJWKS(source)
+			if err != nil {
+				log.Errorf("jwt: refresh jwks from %s err: %v", source, err)
+				continue
+			}
+			s.Update(keys)
+		}
+	}
+}
+
+// jwksClient 获取 JWKS 的 http client
+var jwksClient = &http.Client{Timeout: 10 * time.Second}
+
+// LoadJWKS 从文件路径或 http(s) URL 加载 JWKS 文档
+func LoadJWKS(source string) ([]*Key, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err = fetch(source)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load jwks from %s: %w", source, err)
+	}
+	return ParseJWKS(data)
+}
+
+func fetch(url string) ([]byte, error) {
+	rsp, err := jwksClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", rsp.Status)
+	}
+	return io.ReadAll(rsp.Body)
+}
+
+// jwk JSON Web Key, 见 RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// ParseJWKS 解析 JWKS 文档中的验签公钥, 忽略用于加密的密钥
+func ParseJWKS(data []byte) ([]*Key, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	var keys []*Key
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("parse jwk %q: %w", k.Kid, err)
+		}
+		alg := k.Alg
+		if alg == "" {
+			alg = AlgorithmOf(pub)
+		}
+		keys = append(keys, &Key{ID: k.Kid, Algorithm: alg, Key: pub})
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 public key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// MarshalJWKS 将密钥的公钥部分编码为 JWKS 文档, 用于对外发布验签公钥, 不支持 HMAC 密钥
+func MarshalJWKS(keys ...*Key) ([]byte, error) {
+	set := jwks{Keys: make([]jwk, 0, len(keys))}
+	for _, k := range keys {
+		j := jwk{Kid: k.ID, Alg: k.Algorithm, Use: "sig"}
+		switch pub := k.verifyKey().(type) {
+		case *rsa.PublicKey:
+			j.Kty = "RSA"
+			j.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			j.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			j.Kty = "EC"
+			j.Crv = pub.Curve.Params().Name
+			j.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			j.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			j.Kty = "OKP"
+			j.Crv = "Ed25519"
+			j.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			return nil, fmt.Errorf("unsupported key type %T of key %q", pub, k.ID)
+		}
+		set.Keys = append(set.Keys, j)
+	}
+	return json.Marshal(set)
+}
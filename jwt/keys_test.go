@@ -0,0 +1,150 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockPrivateKeys(t *testing.T) map[string]crypto.Signer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	return map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey, AlgEdDSA: edKey}
+}
+
+func TestParsePEM(t *testing.T) {
+	for alg, priv := range mockPrivateKeys(t) {
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		require.Nil(t, err)
+		parsed, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		assert.Nil(t, err, alg)
+		assert.Equal(t, alg, AlgorithmOf(parsed))
+
+		der, err = x509.MarshalPKIXPublicKey(priv.Public())
+		require.Nil(t, err)
+		pub, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		assert.Nil(t, err, alg)
+		assert.Equal(t, priv.Public(), pub)
+	}
+	_, err := ParsePrivateKeyPEM([]byte("abc"))
+	assert.NotNil(t, err)
+	_, err = ParsePublicKeyPEM([]byte("abc"))
+	assert.NotNil(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	var keys []*Key
+	for alg, priv := range mockPrivateKeys(t) {
+		keys = append(keys, &Key{ID: alg, Algorithm: alg, Key: priv})
+	}
+	data, err := MarshalJWKS(keys...)
+	assert.Nil(t, err)
+	parsed, err := ParseJWKS(data)
+	assert.Nil(t, err)
+	assert.Len(t, parsed, len(keys))
+	for _, k := range parsed {
+		for _, want := range keys {
+			if want.ID == k.ID {
+				assert.Equal(t, want.Algorithm, k.Algorithm)
+				assert.Equal(t, want.verifyKey(), k.Key)
+			}
+		}
+	}
+
+	_, err = MarshalJWKS(&Key{ID: "hmac", Algorithm: "HS256", Key: []byte("secret")})
+	assert.NotNil(t, err)
+	_, err = ParseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"a"}]}`))
+	assert.NotNil(t, err)
+	parsed, err = ParseJWKS([]byte(`{"keys":[{"kty":"RSA","use":"enc"}]}`))
+	assert.Nil(t, err)
+	assert.Empty(t, parsed)
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	static := &Key{ID: "static", Algorithm: "HS256", Key: []byte("secret")}
+	s := NewKeySet(20*time.Millisecond, static)
+	s.Update([]*Key{{ID: "k1"}})
+	_, ok := s.Lookup("k1")
+	assert.True(t, ok)
+
+	// k1 is rotated out, but still valid in grace period.
+	s.Update([]*Key{{ID: "k2"}})
+	_, ok = s.Lookup("k1")
+	assert.True(t, ok)
+	_, ok = s.Lookup("k2")
+	assert.True(t, ok)
+
+	time.Sleep(25 * time.Millisecond)
+	_, ok = s.Lookup("k1")
+	assert.False(t, ok)
+	s.Update([]*Key{{ID: "k2"}})
+	_, ok = s.Lookup("k1")
+	assert.False(t, ok)
+	k, ok := s.Lookup("static")
+	assert.True(t, ok)
+	assert.Equal(t, static, k)
+}
+
+func TestNewJWKSKeySet(t *testing.T) {
+	keys := mockPrivateKeys(t)
+	first, err := MarshalJWKS(&Key{ID: "k1", Algorithm: "ES256", Key: keys["ES256"]})
+	require.Nil(t, err)
+	second, err := MarshalJWKS(&Key{ID: "k2", Algorithm: AlgEdDSA, Key: keys[AlgEdDSA]})
+	require.Nil(t, err)
+	var rotated int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&rotated) == 1 {
+			_, _ = w.Write(second)
+			return
+		}
+		_, _ = w.Write(first)
+	}))
+	defer srv.Close()
+
+	s, err := NewJWKSKeySet(srv.URL, 5*time.Millisecond, time.Hour)
+	require.Nil(t, err)
+	defer s.Close()
+	_, ok := s.Lookup("k1")
+	assert.True(t, ok)
+
+	atomic.StoreInt32(&rotated, 1)
+	assert.Eventually(t, func() bool {
+		_, ok := s.Lookup("k2")
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	_, ok = s.Lookup("k1")
+	assert.True(t, ok)
+
+	_, err = NewJWKSKeySet("./not_exist.json", 0, 0)
+	assert.NotNil(t, err)
+}
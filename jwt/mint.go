@@ -0,0 +1,217 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"trpc.group/trpc-go/trpc-go"
+)
+
+const (
+	// 服务间 token 默认有效期
+	defaultMintTTL = 5 * time.Minute
+	// 缓存 token 的最大数量, 超过时清理过期的 token
+	maxMintedTokens = 10000
+	// 透传终端用户信息的自定义数据 key
+	endUserKey = "user"
+)
+
+// ErrEndUserExpired 透传的终端用户 token 已过期
+var ErrEndUserExpired = fmt.Errorf("end user token expired")
+
+// EndUser 服务间 token 中透传的终端用户信息
+type EndUser struct {
+	Subject string      `json:"sub,omitempty" mapstructure:"sub"`
+	Issuer  string      `json:"iss,omitempty" mapstructure:"iss"`
+	Custom  interface{} `json:"custom,omitempty" mapstructure:"custom"`
+}
+
+// GetEndUser 获取服务间 token 中透传的终端用户信息
+func GetEndUser(ctx context.Context) (*EndUser, bool) {
+	c, ok := GetClaims(ctx)
+	if !ok || c.Type != TokenTypeService {
+		return nil, false
+	}
+	custom, ok := c.Custom.(map[string]interface{})
+	if !ok || custom[endUserKey] == nil {
+		return nil, false
+	}
+	u := &EndUser{}
+	if err := mapstructure.Decode(custom[endUserKey], u); err != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+// mintOptions 签发服务间 token 的选项
+type mintOptions struct {
+	ttl           time.Duration
+	identity      string
+	propagateUser bool
+}
+
+// MintOption 设置签发服务间 token 的选项
+type MintOption func(*mintOptions)
+
+// WithMintTTL 设置服务间 token 的有效期, 默认 5 分钟
+func WithMintTTL(ttl time.Duration) MintOption {
+	return func(o *mintOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithIdentity 设置主调服务的身份, 写入 token 的 sub, 默认为主调服务名
+func WithIdentity(identity string) MintOption {
+	return func(o *mintOptions) {
+		o.identity = identity
+	}
+}
+
+// WithPropagateUser 设置是否透传服务端校验通过的终端用户信息
+func WithPropagateUser(propagate bool) MintOption {
+	return func(o *mintOptions) {
+		o.propagateUser = propagate
+	}
+}
+
+// minter 签发并缓存服务间 token
+type minter struct {
+	signer Signer
+	mintOptions
+
+	mu     sync.Mutex
+	tokens map[mintKey]*mintedToken
+}
+
+// mintKey token 缓存 key, 透传终端用户信息时包含终端用户的 token
+type mintKey struct {
+	caller    string
+	callee    string
+	userToken string
+}
+
+type mintedToken struct {
+	token     string
+	refreshAt time.Time
+	expireAt  time.Time
+}
+
+// MintTokenFunc 返回签发服务间 token 的函数, 用于 WithTokenFunc。signer 需实现 ClaimsSigner。
+// token 的类型为 TokenTypeService, sub 为主调服务身份, aud 为被调服务名, 缓存至临近过期时重新签发。
+// 透传终端用户信息时, token 不晚于终端用户的 token 过期, 终端用户的 token 已过期时返回 ErrEndUserExpired。
+func MintTokenFunc(signer Signer, opts ...MintOption) func(ctx context.Context) (string, error) {
+	m := &minter{
+		signer:      signer,
+		mintOptions: mintOptions{ttl: defaultMintTTL},
+		tokens:      make(map[mintKey]*mintedToken),
+	}
+	for _, opt := range opts {
+		opt(&m.mintOptions)
+	}
+	return m.token
+}
+
+// WithMinting 客户端为每次调用签发服务间 token
+func WithMinting(signer Signer, opts ...MintOption) ClientOption {
+	return WithTokenFunc(MintTokenFunc(signer, opts...))
+}
+
+func (m *minter) token(ctx context.Context) (string, error) {
+	msg := trpc.Message(ctx)
+	key := mintKey{caller: m.identity, callee: msg.CalleeServiceName()}
+	if key.caller == "" {
+		key.caller = msg.CallerServiceName()
+	}
+	now := time.Now()
+	var user *Claims
+	if m.propagateUser {
+		user, _ = GetClaims(ctx)
+		key.userToken = GetToken(ctx)
+		// 服务端校验时允许时钟偏差, 已过期的终端用户 token 不再签发
+		if user != nil && !user.ExpiresAt.IsZero() && !now.Before(user.ExpiresAt) {
+			return "", ErrEndUserExpired
+		}
+	}
+
+	m.mu.Lock()
+	t, ok := m.tokens[key]
+	m.mu.Unlock()
+	if ok && now.Before(t.refreshAt) {
+		return t.token, nil
+	}
+
+	c := &Claims{
+		Subject:   key.caller,
+		Audience:  []string{key.callee},
+		IssuedAt:  now,
+		ExpiresAt: now.Add(m.ttl),
+		Type:      TokenTypeService,
+	}
+	if user != nil {
+		c.Custom = map[string]interface{}{endUserKey: &EndUser{
+			Subject: user.Subject,
+			Issuer:  user.Issuer,
+			Custom:  user.Custom,
+		}}
+		// 终端用户的 token 先于服务间 token 过期时, 服务间 token 随之过期
+		if !user.ExpiresAt.IsZero() && user.ExpiresAt.Before(c.ExpiresAt) {
+			c.ExpiresAt = user.ExpiresAt
+		}
+	}
+	token, err := m.sign(c)
+	if err != nil {
+		return "", err
+	}
+	m.store(key, &mintedToken{
+		token:     token,
+		refreshAt: now.Add(c.ExpiresAt.Sub(now) * 4 / 5),
+		expireAt:  c.ExpiresAt,
+	}, now)
+	return token, nil
+}
+
+// sign 签发 token, signer 未实现 ClaimsSigner 时无法写入 token 类型, 签发的 token 会被当作终端用户的 token, 因此拒绝签发
+func (m *minter) sign(c *Claims) (string, error) {
+	s, ok := m.signer.(ClaimsSigner)
+	if !ok {
+		return "", fmt.Errorf("mint token: signer does not implement ClaimsSigner")
+	}
+	token, err := s.SignClaims(c)
+	if err != nil {
+		return "", fmt.Errorf("mint token: %w", err)
+	}
+	return token, nil
+}
+
+func (m *minter) store(key mintKey, t *mintedToken, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tokens) >= maxMintedTokens {
+		for k, v := range m.tokens {
+			if !now.Before(v.expireAt) {
+				delete(m.tokens, k)
+			}
+		}
+		if len(m.tokens) >= maxMintedTokens {
+			m.tokens = make(map[mintKey]*mintedToken)
+		}
+	}
+	m.tokens[key] = t
+}
@@ -0,0 +1,147 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+func newClientCtx(ctx context.Context, caller, callee string) context.Context {
+	ctx, msg := codec.WithNewMessage(ctx)
+	msg.WithCallerServiceName(caller)
+	msg.WithCalleeServiceName(callee)
+	return ctx
+}
+
+func mockServiceVerifier(opts ...SignOption) ClaimsVerifier {
+	return NewJwtSign([]byte("123456"), time.Hour, "issuer",
+		append([]SignOption{WithTokenTypes(TokenTypeService)}, opts...)...).(ClaimsVerifier)
+}
+
+func TestMintTokenFunc(t *testing.T) {
+	sign := mockSigner()
+	token, err := MintTokenFunc(sign, WithMintTTL(time.Minute))(
+		newClientCtx(context.Background(), "trpc.app.a.A", "trpc.app.b.B"))
+	assert.Nil(t, err)
+	// Service tokens are not accepted as end-user tokens.
+	_, err = sign.(ClaimsVerifier).VerifyClaims(token)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+	c, err := mockServiceVerifier().VerifyClaims(token)
+	assert.Nil(t, err)
+	assert.Equal(t, TokenTypeService, c.Type)
+	assert.Equal(t, "trpc.app.a.A", c.Subject)
+	assert.Equal(t, []string{"trpc.app.b.B"}, c.Audience)
+	assert.Equal(t, "issuer", c.Issuer)
+	assert.True(t, c.ExpiresAt.Before(time.Now().Add(time.Minute)))
+
+	// Cached until near expiry. exp has second precision, so tokens of such short ttl are only compared.
+	mint := MintTokenFunc(sign, WithMintTTL(50*time.Millisecond))
+	token, err = mint(newClientCtx(context.Background(), "trpc.app.a.A", "trpc.app.b.B"))
+	assert.Nil(t, err)
+	cached, err := mint(newClientCtx(context.Background(), "trpc.app.a.A", "trpc.app.b.B"))
+	assert.Nil(t, err)
+	assert.Equal(t, token, cached)
+	other, err := mint(newClientCtx(context.Background(), "trpc.app.a.A", "trpc.app.c.C"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, other)
+	time.Sleep(45 * time.Millisecond)
+	refreshed, err := mint(newClientCtx(context.Background(), "trpc.app.a.A", "trpc.app.b.B"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, refreshed)
+
+	// Identity overrides caller service name.
+	token, err = MintTokenFunc(sign, WithIdentity("spiffe://a"))(
+		newClientCtx(context.Background(), "trpc.app.a.A", "trpc.app.b.B"))
+	assert.Nil(t, err)
+	c, err = mockServiceVerifier().VerifyClaims(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "spiffe://a", c.Subject)
+
+	_, err = MintTokenFunc(NewJwtSign(nil, time.Hour, "issuer"))(context.Background())
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+	// Signers which can not write the token type are rejected.
+	_, err = MintTokenFunc(struct{ Signer }{sign})(context.Background())
+	assert.NotNil(t, err)
+}
+
+func TestMintTokenFunc_EndUserExpiry(t *testing.T) {
+	sign := mockSigner()
+	mint := MintTokenFunc(sign, WithPropagateUser(true))
+	userCtx := func(exp time.Time) context.Context {
+		ctx := context.WithValue(context.Background(), AuthClaimsCtxKey, &Claims{Subject: "10001", ExpiresAt: exp})
+		ctx = context.WithValue(ctx, AuthTokenCtxKey, exp.String())
+		return newClientCtx(ctx, "trpc.app.a.A", "trpc.app.b.B")
+	}
+
+	// The service token expires with the end-user token.
+	exp := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	token, err := mint(userCtx(exp))
+	assert.Nil(t, err)
+	c, err := mockServiceVerifier().VerifyClaims(token)
+	assert.Nil(t, err)
+	assert.Equal(t, exp.Unix(), c.ExpiresAt.Unix())
+
+	// Expired end-user tokens, which may still pass the leeway of the server, are not minted.
+	_, err = mint(userCtx(time.Now().Add(-time.Second)))
+	assert.ErrorIs(t, err, ErrEndUserExpired)
+}
+
+func TestClientFilter_Minting(t *testing.T) {
+	sign := mockSigner()
+	SetDefaultSigner(sign)
+	userToken, err := sign.(ClaimsSigner).SignClaims(&Claims{Subject: "10001", Custom: mockUserInfo()})
+	assert.Nil(t, err)
+
+	// The upstream server verifies the end-user token.
+	serverCtx := trpc.BackgroundContext()
+	trpc.Message(serverCtx).WithServerMetaData(codec.MetaData{"authorization": []byte(userToken)})
+	source := WithTokenSources(DefaultTokenTarget)
+	_, err = ServerFilter(source)(serverCtx, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// Call downstream with a minted token.
+		clientCtx := newClientCtx(ctx, "trpc.app.a.A", "trpc.app.b.B")
+		f := ClientFilter(WithMinting(sign, WithPropagateUser(true)))
+		assert.Nil(t, f(clientCtx, nil, nil, func(ctx context.Context, req, rsp interface{}) error {
+			// The downstream server verifies the minted token.
+			downstreamCtx := trpc.BackgroundContext()
+			trpc.Message(downstreamCtx).WithServerMetaData(trpc.Message(ctx).ClientMetaData())
+			SetDefaultSigner(mockServiceVerifier(WithAudiences("trpc.app.b.B")).(Signer))
+			_, err := ServerFilter(source)(downstreamCtx, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+				c, _ := GetClaims(ctx)
+				assert.Equal(t, "trpc.app.a.A", c.Subject)
+				u, ok := GetEndUser(ctx)
+				assert.True(t, ok)
+				assert.Equal(t, "10001", u.Subject)
+				var info userInfo
+				assert.Nil(t, mapstructure.Decode(u.Custom, &info))
+				assert.Equal(t, *mockUserInfo(), info)
+				return nil, nil
+			})
+			return err
+		}))
+		return nil, nil
+	})
+	assert.Nil(t, err)
+
+	f := ClientFilter(WithMinting(NewJwtSign(nil, time.Hour, "issuer")))
+	err = f(trpc.BackgroundContext(), nil, nil, func(ctx context.Context, req, rsp interface{}) error { return nil })
+	assert.Equal(t, errs.RetClientValidateFail, errs.Code(err))
+}
@@ -0,0 +1,66 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+)
+
+// RefreshPair 校验 refresh token 并签发新的 access token 及 refresh token。
+// store 不为 nil 时, 拒绝已吊销的 refresh token, 并吊销旧的 refresh token, 每个 refresh token 只能使用一次。
+func RefreshPair(ctx context.Context, signer Signer, store RevocationStore,
+	refreshToken string) (access, refresh string, err error) {
+	ps, ok := signer.(PairSigner)
+	if !ok {
+		return "", "", fmt.Errorf("signer %T does not support refresh token", signer)
+	}
+	c, err := ps.VerifyRefresh(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if store != nil {
+		// 检查并吊销须是原子的, 否则并发的刷新请求可以使用同一个 refresh token
+		if c.ID == "" {
+			return "", "", fmt.Errorf("%w: %s", ErrMissingClaim, ClaimID)
+		}
+		revoked, err := store.RevokeIfAbsent(ctx, c.ID, c.ExpiresAt)
+		if err != nil {
+			return "", "", err
+		}
+		if !revoked {
+			return "", "", ErrTokenRevoked
+		}
+	}
+	return ps.SignPair(&Claims{
+		Subject:  c.Subject,
+		Audience: c.Audience,
+		Custom:   c.Custom,
+	})
+}
+
+// checkRevoked 检查 token 是否已吊销, 没有 jti 的 token 不能吊销
+func checkRevoked(ctx context.Context, store RevocationStore, c *Claims) error {
+	if c.ID == "" {
+		return nil
+	}
+	revoked, err := store.IsRevoked(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignPair(t *testing.T) {
+	sign := NewJwtSign([]byte("123456"), time.Minute, "issuer", WithRefreshExpired(time.Hour))
+	access, refresh, err := sign.(PairSigner).SignPair(&Claims{Subject: "10001", Custom: mockUserInfo()})
+	assert.Nil(t, err)
+
+	c, err := sign.(ClaimsVerifier).VerifyClaims(access)
+	assert.Nil(t, err)
+	assert.Equal(t, TokenTypeAccess, c.Type)
+	assert.True(t, c.ExpiresAt.Before(time.Now().Add(2*time.Minute)))
+	_, err = sign.(PairSigner).VerifyRefresh(access)
+	assert.Equal(t, ErrNotRefreshToken, err)
+
+	rc, err := sign.(PairSigner).VerifyRefresh(refresh)
+	assert.Nil(t, err)
+	assert.Equal(t, "10001", rc.Subject)
+	assert.NotEqual(t, c.ID, rc.ID)
+	assert.True(t, rc.ExpiresAt.After(time.Now().Add(30*time.Minute)))
+	_, err = sign.Verify(refresh)
+	assert.Equal(t, ErrRefreshToken, err)
+}
+
+func TestRefreshPair(t *testing.T) {
+	ctx := context.Background()
+	sign := mockSigner()
+	_, refresh, err := sign.(PairSigner).SignPair(&Claims{Subject: "10001", Audience: []string{"app"}})
+	assert.Nil(t, err)
+
+	store := NewMemoryRevocationStore(0)
+	access, next, err := RefreshPair(ctx, sign, store, refresh)
+	assert.Nil(t, err)
+	c, err := sign.(ClaimsVerifier).VerifyClaims(access)
+	assert.Nil(t, err)
+	assert.Equal(t, "10001", c.Subject)
+	assert.Equal(t, []string{"app"}, c.Audience)
+
+	// A refresh token can only be used once.
+	_, _, err = RefreshPair(ctx, sign, store, refresh)
+	assert.Equal(t, ErrTokenRevoked, err)
+	_, _, err = RefreshPair(ctx, sign, store, next)
+	assert.Nil(t, err)
+
+	// Concurrent refreshes with the same refresh token, only one of them succeeds.
+	_, refresh, err = sign.(PairSigner).SignPair(&Claims{Subject: "10001"})
+	assert.Nil(t, err)
+	var (
+		wg        sync.WaitGroup
+		succeeded int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := RefreshPair(ctx, sign, store, refresh); err == nil {
+				atomic.AddInt32(&succeeded, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded)
+
+	// Access tokens can not be used to refresh.
+	_, _, err = RefreshPair(ctx, sign, nil, access)
+	assert.Equal(t, ErrNotRefreshToken, err)
+}
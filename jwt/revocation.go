@@ -0,0 +1,176 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// 内存吊销存储的默认容量
+	defaultRevocationCapacity = 100000
+	// 没有过期时间的 token 在内存吊销存储中保留的时长
+	defaultRevocationTTL = 24 * time.Hour
+)
+
+var (
+	// ErrNoRevocationStore 未设置吊销存储
+	ErrNoRevocationStore = fmt.Errorf("no revocation store")
+	// ErrTokenRevoked token 已吊销
+	ErrTokenRevoked = fmt.Errorf("token revoked")
+)
+
+// builtinRevocationStore 内置的内存吊销存储, 插件配置 revocation 时替换为配置的容量及保留时长
+var builtinRevocationStore = NewMemoryRevocationStore(0)
+
+// DefaultRevocationStore 默认的吊销存储, 默认为容量 100000 的内存存储, 为 nil 时不检查 token 是否吊销
+var DefaultRevocationStore = builtinRevocationStore
+
+// SetDefaultRevocationStore 设置默认的吊销存储
+func SetDefaultRevocationStore(s RevocationStore) {
+	if s != nil {
+		DefaultRevocationStore = s
+	}
+}
+
+// RevocationStore 已吊销 token 的存储, 以 jti 为 key, 可以基于 Redis 等实现以在多个实例间共享
+type RevocationStore interface {
+	// Revoke 吊销 jti, expireAt 之后 token 已过期, 存储可以删除该记录
+	Revoke(ctx context.Context, jti string, expireAt time.Time) error
+	// IsRevoked 是否已吊销
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeIfAbsent 原子地吊销未吊销的 jti, 返回是否由本次调用吊销, jti 已吊销时返回 false,
+	// 如基于 Redis 的实现可以使用 SET NX
+	RevokeIfAbsent(ctx context.Context, jti string, expireAt time.Time) (bool, error)
+}
+
+// Revoke 吊销 claims 对应的 token, 使用 DefaultRevocationStore
+func Revoke(ctx context.Context, c *Claims) error {
+	if DefaultRevocationStore == nil {
+		return ErrNoRevocationStore
+	}
+	return revoke(ctx, DefaultRevocationStore, c)
+}
+
+func revoke(ctx context.Context, store RevocationStore, c *Claims) error {
+	if c.ID == "" {
+		return fmt.Errorf("%w: %s", ErrMissingClaim, ClaimID)
+	}
+	return store.Revoke(ctx, c.ID, c.ExpiresAt)
+}
+
+// memoryRevocationStore 内存吊销存储, 按过期时间淘汰, 超过容量时淘汰最久未访问的记录
+type memoryRevocationStore struct {
+	capacity int
+	ttl      time.Duration
+	mu       sync.Mutex
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type revokedItem struct {
+	jti      string
+	expireAt time.Time
+}
+
+// MemoryRevocationOption 内存吊销存储的选项
+type MemoryRevocationOption func(*memoryRevocationStore)
+
+// WithRevocationTTL 设置没有过期时间的 token 的吊销记录保留时长, 默认 24 小时
+func WithRevocationTTL(ttl time.Duration) MemoryRevocationOption {
+	return func(s *memoryRevocationStore) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// NewMemoryRevocationStore 构造内存吊销存储, capacity 小于等于 0 时为默认容量 100000。
+// 超过容量时最久未访问的记录会被淘汰, 被淘汰的 token 在过期前重新有效, 多实例部署时应使用共享存储。
+func NewMemoryRevocationStore(capacity int, opts ...MemoryRevocationOption) RevocationStore {
+	if capacity <= 0 {
+		capacity = defaultRevocationCapacity
+	}
+	s := &memoryRevocationStore{
+		capacity: capacity,
+		ttl:      defaultRevocationTTL,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Revoke 吊销 jti
+func (s *memoryRevocationStore) Revoke(_ context.Context, jti string, expireAt time.Time) error {
+	s.revoke(jti, expireAt, false)
+	return nil
+}
+
+// RevokeIfAbsent 吊销未吊销的 jti
+func (s *memoryRevocationStore) RevokeIfAbsent(_ context.Context, jti string, expireAt time.Time) (bool, error) {
+	return s.revoke(jti, expireAt, true), nil
+}
+
+// revoke 吊销 jti, ifAbsent 为 true 时不修改未过期的记录, 返回是否由本次调用吊销
+func (s *memoryRevocationStore) revoke(jti string, expireAt time.Time, ifAbsent bool) bool {
+	now := time.Now()
+	if expireAt.IsZero() {
+		expireAt = now.Add(s.ttl)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[jti]; ok {
+		item := e.Value.(*revokedItem)
+		revoked := now.Before(item.expireAt)
+		if ifAbsent && revoked {
+			return false
+		}
+		item.expireAt = expireAt
+		s.ll.MoveToFront(e)
+		return !revoked
+	}
+	s.items[jti] = s.ll.PushFront(&revokedItem{jti: jti, expireAt: expireAt})
+	for s.ll.Len() > s.capacity {
+		s.remove(s.ll.Back())
+	}
+	return true
+}
+
+// IsRevoked 是否已吊销
+func (s *memoryRevocationStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[jti]
+	if !ok {
+		return false, nil
+	}
+	if !time.Now().Before(e.Value.(*revokedItem).expireAt) {
+		s.remove(e)
+		return false, nil
+	}
+	s.ll.MoveToFront(e)
+	return true, nil
+}
+
+func (s *memoryRevocationStore) remove(e *list.Element) {
+	s.ll.Remove(e)
+	delete(s.items, e.Value.(*revokedItem).jti)
+}
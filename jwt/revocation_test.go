@@ -0,0 +1,107 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryRevocationStore(2)
+	assert.Nil(t, s.Revoke(ctx, "a", time.Now().Add(time.Hour)))
+	assert.Nil(t, s.Revoke(ctx, "b", time.Now().Add(10*time.Millisecond)))
+	revoked, err := s.IsRevoked(ctx, "a")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+	revoked, _ = s.IsRevoked(ctx, "c")
+	assert.False(t, revoked)
+
+	// Expired records are removed.
+	time.Sleep(15 * time.Millisecond)
+	revoked, _ = s.IsRevoked(ctx, "b")
+	assert.False(t, revoked)
+
+	// The least recently used record is evicted.
+	assert.Nil(t, s.Revoke(ctx, "c", time.Time{}))
+	assert.Nil(t, s.Revoke(ctx, "d", time.Time{}))
+	revoked, _ = s.IsRevoked(ctx, "a")
+	assert.False(t, revoked)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, s.Revoke(ctx, fmt.Sprint(i), time.Time{}))
+	}
+	assert.Equal(t, 2, s.(*memoryRevocationStore).ll.Len())
+
+	// Only absent or expired records are revoked.
+	ok, err := s.RevokeIfAbsent(ctx, "e", time.Now().Add(10*time.Millisecond))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = s.RevokeIfAbsent(ctx, "e", time.Now().Add(time.Hour))
+	assert.False(t, ok)
+	time.Sleep(15 * time.Millisecond)
+	ok, _ = s.RevokeIfAbsent(ctx, "e", time.Now().Add(time.Hour))
+	assert.True(t, ok)
+
+	// Records of tokens without exp are kept for the ttl.
+	s = NewMemoryRevocationStore(0, WithRevocationTTL(10*time.Millisecond))
+	assert.Nil(t, s.Revoke(ctx, "f", time.Time{}))
+	revoked, _ = s.IsRevoked(ctx, "f")
+	assert.True(t, revoked)
+	time.Sleep(15 * time.Millisecond)
+	revoked, _ = s.IsRevoked(ctx, "f")
+	assert.False(t, revoked)
+}
+
+func TestServerFilter_Revocation(t *testing.T) {
+	sign := mockSigner()
+	SetDefaultSigner(sign)
+	token, err := sign.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	store := NewMemoryRevocationStore(0)
+	f := ServerFilter(WithTokenSources(DefaultTokenTarget), WithRevocationStore(store))
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithServerMetaData(codec.MetaData{"authorization": []byte(token)})
+
+	var claims *Claims
+	_, err = f(ctx, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		claims, _ = GetClaims(ctx)
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, claims.ID)
+
+	assert.Nil(t, store.Revoke(ctx, claims.ID, claims.ExpiresAt))
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Equal(t, errs.RetServerAuthFail, errs.Code(err))
+
+	// The default store is used if not set, which is a memory store by default.
+	defer func() { DefaultRevocationStore = builtinRevocationStore }()
+	DefaultRevocationStore = NewMemoryRevocationStore(0)
+	_, err = ServerFilter(WithTokenSources(DefaultTokenTarget))(ctx, nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+	assert.Nil(t, Revoke(ctx, claims))
+	_, err = ServerFilter(WithTokenSources(DefaultTokenTarget))(ctx, nil, fakeServerHandleFunc)
+	assert.NotNil(t, err)
+	assert.ErrorIs(t, Revoke(ctx, &Claims{}), ErrMissingClaim)
+	DefaultRevocationStore = nil
+	assert.Equal(t, ErrNoRevocationStore, Revoke(ctx, claims))
+}
@@ -14,10 +14,12 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
-	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
@@ -36,51 +38,234 @@ type jwtSign struct {
 	Secret  []byte
 	Expired time.Duration
 	Issuer  string
+
+	signingKey *Key    // 签名密钥, 未设置时使用 Secret 以 HS512 签名
+	keys       *KeySet // 验签密钥集合, 按 token 头部的 kid 查找
+	validation         // 标准声明的校验规则
+
+	refreshExpired time.Duration // refresh token 的有效期
+}
+
+// 默认的 refresh token 有效期
+const defaultRefreshExpired = 7 * 24 * time.Hour
+
+// SignOption 签名器选项
+type SignOption func(*jwtSign)
+
+// WithSigningKey 设置签名密钥, 如 RS256 私钥, key.ID 不为空时写入 token 头部的 kid
+func WithSigningKey(key *Key) SignOption {
+	return func(s *jwtSign) {
+		s.signingKey = key
+	}
+}
+
+// WithKeySet 设置验签密钥集合, 如从 JWKS 加载的公钥
+func WithKeySet(keys *KeySet) SignOption {
+	return func(s *jwtSign) {
+		s.keys = keys
+	}
+}
+
+// WithIssuers 设置预期的签发者, 未设置时不校验 iss
+func WithIssuers(issuers ...string) SignOption {
+	return func(s *jwtSign) {
+		s.issuers = issuers
+	}
+}
+
+// WithAudiences 设置预期的受众, token 的 aud 包含其中之一即可, 未设置时不校验 aud
+func WithAudiences(audiences ...string) SignOption {
+	return func(s *jwtSign) {
+		s.audiences = audiences
+	}
+}
+
+// WithLeeway 设置校验 exp, nbf 时允许的时钟偏差
+func WithLeeway(leeway time.Duration) SignOption {
+	return func(s *jwtSign) {
+		s.leeway = leeway
+	}
 }
 
-// NewJwtSign 构造 jwt 签名
-func NewJwtSign(secret []byte, expired time.Duration, issuer string) Signer {
-	return &jwtSign{
-		Secret:  secret,
-		Expired: expired,
-		Issuer:  issuer,
+// WithRequiredClaims 设置必需的声明, 如 ClaimSubject, ClaimAudience, 非标准声明在自定义数据中查找
+func WithRequiredClaims(names ...string) SignOption {
+	return func(s *jwtSign) {
+		s.required = names
 	}
 }
 
-// claims 元数据
-type claims struct {
-	jwt.StandardClaims
-	// 业务自定义的数据
-	Custom interface{} `json:"custom,omitempty"`
+// WithTokenTypes 设置 VerifyClaims 接受的 token 类型, 默认只接受 TokenTypeAccess,
+// 被调服务接受客户端签发的服务间 token 时需包含 TokenTypeService。refresh token 总是被拒绝。
+func WithTokenTypes(types ...string) SignOption {
+	return func(s *jwtSign) {
+		s.types = types
+	}
+}
+
+// WithRefreshExpired 设置 refresh token 的有效期, 默认 7 天
+func WithRefreshExpired(expired time.Duration) SignOption {
+	return func(s *jwtSign) {
+		s.refreshExpired = expired
+	}
+}
+
+// NewJwtSign 构造 jwt 签名。secret 不为空且未通过 WithSigningKey 设置签名密钥时, 以 HS512 签名;
+// 只验签的服务可以传入空 secret, 仅通过 WithKeySet 设置公钥。
+func NewJwtSign(secret []byte, expired time.Duration, issuer string, opts ...SignOption) Signer {
+	s := &jwtSign{
+		Secret:         secret,
+		Expired:        expired,
+		Issuer:         issuer,
+		refreshExpired: defaultRefreshExpired,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.signingKey == nil && len(secret) > 0 {
+		s.signingKey = &Key{Algorithm: defaultAlgorithm, Key: secret}
+	}
+	if s.keys == nil {
+		s.keys = NewKeySet(0)
+	}
+	return s
 }
 
 // Sign 生成签名
 func (t *jwtSign) Sign(custom interface{}) (string, error) {
+	return t.SignClaims(&Claims{Custom: custom})
+}
+
+// SignClaims 生成签名, 未设置的 iss, iat, exp, jti 使用签名器的默认值
+func (t *jwtSign) SignClaims(c *Claims) (string, error) {
+	if t.signingKey == nil {
+		return "", ErrNoSigningKey
+	}
+	method := jwt.GetSigningMethod(t.signingKey.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("unsupported algorithm %q", t.signingKey.Algorithm)
+	}
+	cl := newClaims(c)
 	now := time.Now()
-	cl := claims{
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: jwt.At(now.Add(t.Expired)),
-			IssuedAt:  jwt.At(now),
-			Issuer:    t.Issuer,
-		},
-		Custom: custom,
+	if cl.Issuer == "" {
+		cl.Issuer = t.Issuer
+	}
+	if cl.IssuedAt == nil {
+		cl.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if cl.ExpiresAt == nil {
+		expired := t.Expired
+		if cl.Type == TokenTypeRefresh {
+			expired = t.refreshExpired
+		}
+		cl.ExpiresAt = jwt.NewNumericDate(now.Add(expired))
+	}
+	if cl.ID == "" {
+		id, err := newTokenID()
+		if err != nil {
+			return "", err
+		}
+		cl.ID = id
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, cl)
-	return token.SignedString(t.Secret)
+	token := jwt.NewWithClaims(method, cl)
+	if t.signingKey.ID != "" {
+		token.Header["kid"] = t.signingKey.ID
+	}
+	return token.SignedString(t.signingKey.Key)
 }
 
 // Verify 校验 token, 成功则返回用户自定义数据
 func (t *jwtSign) Verify(tokenStr string) (interface{}, error) {
-	// 方法内部主要是具体的解码和校验的过程
-	token, err := jwt.ParseWithClaims(tokenStr, &claims{},
-		func(token *jwt.Token) (i interface{}, err error) {
-			return t.Secret, nil
-		})
+	c, err := t.VerifyClaims(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	return c.Custom, nil
+}
+
+// SignPair 签发 access token 及 refresh token, 两者的 jti 不同
+func (t *jwtSign) SignPair(c *Claims) (string, string, error) {
+	ac, rc := *c, *c
+	ac.Type, rc.Type = TokenTypeAccess, TokenTypeRefresh
+	ac.ID, rc.ID = "", ""
+	rc.ExpiresAt = time.Time{}
+	access, err := t.SignClaims(&ac)
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err := t.SignClaims(&rc)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// VerifyClaims 校验 access token 的签名, 类型及标准声明, 成功则返回完整 claims
+func (t *jwtSign) VerifyClaims(tokenStr string) (*Claims, error) {
+	c, err := t.parse(tokenStr)
 	if err != nil {
 		return nil, err
 	}
-	if claim, ok := token.Claims.(*claims); ok && token.Valid {
-		return claim.Custom, nil
+	if c.Type == TokenTypeRefresh {
+		return nil, ErrRefreshToken
+	}
+	if err := t.validateType(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// VerifyRefresh 校验 refresh token, 成功则返回完整 claims
+func (t *jwtSign) VerifyRefresh(tokenStr string) (*Claims, error) {
+	c, err := t.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if c.Type != TokenTypeRefresh {
+		return nil, ErrNotRefreshToken
+	}
+	return c, nil
+}
+
+// parse 校验 token 的签名及标准声明
+func (t *jwtSign) parse(tokenStr string) (*Claims, error) {
+	// 方法内部主要是具体的解码和校验的过程, iss, aud 由 validate 校验
+	parser := jwt.NewParser(jwt.WithLeeway(t.leeway))
+	token, err := parser.ParseWithClaims(tokenStr, &claims{}, t.verifyKey)
+	if err != nil {
+		return nil, err
+	}
+	claim, ok := token.Claims.(*claims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	c := claim.export()
+	if err := t.validate(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// verifyKey 按 kid 查找验签密钥, 并校验 token 的算法与密钥一致, 防止算法混淆
+func (t *jwtSign) verifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key := t.signingKey
+	if key == nil || key.ID != kid {
+		var ok bool
+		if key, ok = t.keys.Lookup(kid); !ok {
+			return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+		}
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected algorithm %q of key %q", token.Method.Alg(), kid)
+	}
+	return key.verifyKey(), nil
+}
+
+// newTokenID 生成随机的 jti
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return nil, ErrInvalidToken
+	return hex.EncodeToString(b), nil
 }
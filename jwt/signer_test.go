@@ -57,3 +57,49 @@ func TestJwtSign(t *testing.T) {
 	assert.Nil(t, err)
 	reflect.DeepEqual(user, ctxUser)
 }
+
+func TestJwtSign_Asymmetric(t *testing.T) {
+	for alg, priv := range mockPrivateKeys(t) {
+		key := &Key{ID: "kid-" + alg, Algorithm: alg, Key: priv}
+		sign := NewJwtSign(nil, time.Hour, "issuer", WithSigningKey(key))
+		token, err := sign.Sign(mockUserInfo())
+		assert.Nil(t, err, alg)
+		_, err = sign.Verify(token)
+		assert.Nil(t, err, alg)
+
+		// A verify-only signer with the public key.
+		verifier := NewJwtSign(nil, time.Hour, "issuer",
+			WithKeySet(NewKeySet(0, &Key{ID: key.ID, Algorithm: alg, Key: priv.Public()})))
+		_, err = verifier.Verify(token)
+		assert.Nil(t, err, alg)
+		_, err = verifier.Sign(mockUserInfo())
+		assert.Equal(t, ErrNoSigningKey, err)
+	}
+}
+
+func TestJwtSign_KeySelection(t *testing.T) {
+	keys := mockPrivateKeys(t)
+	k1 := &Key{ID: "k1", Algorithm: "RS256", Key: keys["RS256"]}
+	k2 := &Key{ID: "k2", Algorithm: "ES256", Key: keys["ES256"]}
+	verifier := NewJwtSign(nil, time.Hour, "issuer", WithKeySet(NewKeySet(0, k1, k2)))
+	for _, k := range []*Key{k1, k2} {
+		token, err := NewJwtSign(nil, time.Hour, "issuer", WithSigningKey(k)).Sign(mockUserInfo())
+		assert.Nil(t, err)
+		_, err = verifier.Verify(token)
+		assert.Nil(t, err)
+	}
+
+	// Unknown kid.
+	token, err := NewJwtSign(nil, time.Hour, "issuer",
+		WithSigningKey(&Key{ID: "k3", Algorithm: "RS256", Key: keys["RS256"]})).Sign(mockUserInfo())
+	assert.Nil(t, err)
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// A token signed by HMAC with the kid of an RSA key is rejected.
+	token, err = NewJwtSign(nil, time.Hour, "issuer",
+		WithSigningKey(&Key{ID: "k1", Algorithm: "HS256", Key: []byte("secret")})).Sign(mockUserInfo())
+	assert.Nil(t, err)
+	_, err = verifier.Verify(token)
+	assert.NotNil(t, err)
+}
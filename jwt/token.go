@@ -0,0 +1,230 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/filter"
+	trpcHttp "trpc.group/trpc-go/trpc-go/http"
+)
+
+// token 来源类型
+const (
+	SourceHeader   = "header"   // HTTP 头部
+	SourceMetadata = "metadata" // trpc 透传信息, 适用于 trpc 等非 HTTP 协议
+	SourceCookie   = "cookie"   // HTTP cookie
+	SourceQuery    = "query"    // HTTP query 参数
+)
+
+// AuthTokenCtxKey 原始 token 的 context key
+const AuthTokenCtxKey = ContextKey("AuthTokenCtxKey")
+
+// TokenSource token 的来源, 客户端使用时为 token 写入的位置
+type TokenSource struct {
+	Type   string `yaml:"type"`   // 来源类型, header, metadata, cookie 或 query
+	Name   string `yaml:"name"`   // 头部名, metadata key, cookie 名或 query 参数名
+	Prefix string `yaml:"prefix"` // token 的前缀, 如 "Bearer "
+}
+
+var (
+	// DefaultTokenSource 默认从 HTTP Authorization 头部获取 Bearer token
+	DefaultTokenSource = TokenSource{Type: SourceHeader, Name: "Authorization", Prefix: "Bearer "}
+	// DefaultTokenTarget 客户端默认将 token 写入 trpc 透传信息
+	DefaultTokenTarget = TokenSource{Type: SourceMetadata, Name: "authorization"}
+)
+
+// validate 校验来源配置, client 为 true 时只允许 header 和 metadata
+func (s TokenSource) validate(client bool) error {
+	if s.Name == "" {
+		return fmt.Errorf("jwt: name of token source %q is empty", s.Type)
+	}
+	switch s.Type {
+	case SourceHeader, SourceMetadata:
+		return nil
+	case SourceCookie, SourceQuery:
+		if !client {
+			return nil
+		}
+	}
+	return fmt.Errorf("jwt: unsupported token source %q", s.Type)
+}
+
+// extract 从请求中获取 token, 来源不适用于当前请求或 token 不存在时返回空字符串
+func (s TokenSource) extract(ctx context.Context) string {
+	var token string
+	switch s.Type {
+	case SourceMetadata:
+		token = string(trpc.Message(ctx).ServerMetaData()[s.Name])
+	case SourceHeader, SourceCookie, SourceQuery:
+		head := trpcHttp.Head(ctx)
+		if head == nil || head.Request == nil {
+			return ""
+		}
+		token = extractHTTP(head, s)
+	}
+	if s.Prefix != "" && !strings.HasPrefix(token, s.Prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(token, s.Prefix)
+}
+
+func extractHTTP(head *trpcHttp.Header, s TokenSource) string {
+	r := head.Request
+	switch s.Type {
+	case SourceHeader:
+		return r.Header.Get(s.Name)
+	case SourceCookie:
+		c, err := r.Cookie(s.Name)
+		if err != nil {
+			return ""
+		}
+		return c.Value
+	default:
+		if r.URL == nil {
+			return ""
+		}
+		return r.URL.Query().Get(s.Name)
+	}
+}
+
+// inject 将 token 写入客户端请求
+func (s TokenSource) inject(ctx context.Context, token string) {
+	msg := trpc.Message(ctx)
+	value := s.Prefix + token
+	switch s.Type {
+	case SourceHeader:
+		// 只在 HTTP 客户端已设置请求头部时写入头部, 避免为 trpc 等协议设置 HTTP 头部, 其他情况写入 metadata
+		if head, ok := msg.ClientReqHead().(*trpcHttp.ClientReqHeader); ok {
+			if head.Header != nil {
+				head.Header.Del(s.Name)
+			}
+			head.AddHeader(s.Name, value)
+			return
+		}
+		fallthrough
+	case SourceMetadata:
+		// 复制一份, 避免修改调用方共享的 metadata
+		md := msg.ClientMetaData().Clone()
+		if md == nil {
+			md = codec.MetaData{}
+		}
+		md[s.Name] = []byte(value)
+		msg.WithClientMetaData(md)
+	}
+}
+
+// ExtractToken 按顺序从 sources 中获取第一个非空的 token
+func ExtractToken(ctx context.Context, sources ...TokenSource) string {
+	for _, s := range sources {
+		if token := s.extract(ctx); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// GetToken 获取服务端校验通过的原始 token
+func GetToken(ctx context.Context) string {
+	token, _ := ctx.Value(AuthTokenCtxKey).(string)
+	return token
+}
+
+// hasMetadataSource sources 中是否有适用于非 HTTP 请求的来源
+func hasMetadataSource(sources []TokenSource) bool {
+	for _, s := range sources {
+		if s.Type == SourceMetadata {
+			return true
+		}
+	}
+	return false
+}
+
+// clientOptions 客户端插件配置
+type clientOptions struct {
+	target    TokenSource
+	tokenFunc func(ctx context.Context) (string, error)
+	callees   []string
+}
+
+// ClientOption 设置客户端参数选项
+type ClientOption func(*clientOptions)
+
+// WithTokenTarget 设置 token 写入的位置, 默认为 DefaultTokenTarget。
+// header 只适用于已设置 thttp.ClientReqHeader 的 HTTP 客户端, 否则写入同名的 metadata。
+func WithTokenTarget(target TokenSource) ClientOption {
+	return func(o *clientOptions) {
+		o.target = target
+	}
+}
+
+// WithTokenFunc 设置获取 token 的函数, 默认透传服务端收到的 token, 返回空字符串时不写入 token
+func WithTokenFunc(f func(ctx context.Context) (string, error)) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenFunc = f
+	}
+}
+
+// WithCallees 只为匹配的被调服务写入 token, 支持 path.Match 通配符, 如 trpc.app.*; 未设置时写入所有被调服务。
+// 透传终端用户的 token 时应只配置可信的被调服务, 避免 token 泄露给无关的服务。
+func WithCallees(callees ...string) ClientOption {
+	return func(o *clientOptions) {
+		o.callees = callees
+	}
+}
+
+// matchCallee 被调服务是否需要写入 token
+func (o *clientOptions) matchCallee(callee string) bool {
+	if len(o.callees) == 0 {
+		return true
+	}
+	for _, pattern := range o.callees {
+		if ok, _ := path.Match(pattern, callee); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientFilter 客户端将 token 写入请求 metadata 或头部
+func ClientFilter(opts ...ClientOption) filter.ClientFilter {
+	o := &clientOptions{
+		target: DefaultTokenTarget,
+		tokenFunc: func(ctx context.Context) (string, error) {
+			return GetToken(ctx), nil
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(ctx context.Context, req, rsp interface{}, handler filter.ClientHandleFunc) error {
+		if !o.matchCallee(trpc.Message(ctx).CalleeServiceName()) {
+			return handler(ctx, req, rsp)
+		}
+		token, err := o.tokenFunc(ctx)
+		if err != nil {
+			return errs.NewFrameError(errs.RetClientValidateFail, "jwt: "+err.Error())
+		}
+		if token != "" {
+			o.target.inject(ctx, token)
+		}
+		return handler(ctx, req, rsp)
+	}
+}
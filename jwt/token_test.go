@@ -0,0 +1,175 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/filter"
+	thttp "trpc.group/trpc-go/trpc-go/http"
+)
+
+func mockToken(t *testing.T) string {
+	sign := mockSigner()
+	SetDefaultSigner(sign)
+	token, err := sign.Sign(mockUserInfo())
+	assert.Nil(t, err)
+	return token
+}
+
+func tokenHandler(t *testing.T, want string) filter.ServerHandleFunc {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, want, GetToken(ctx))
+		return nil, nil
+	}
+}
+
+func TestServerFilter_Metadata(t *testing.T) {
+	token := mockToken(t)
+	f := ServerFilter(
+		WithExcludePathSet(map[string]bool{"/trpc.app.server.Greeter/Login": true}),
+		WithTokenSources(TokenSource{Type: SourceMetadata, Name: "authorization"}))
+
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithServerRPCName("/trpc.app.server.Greeter/Hello")
+	msg.WithServerMetaData(codec.MetaData{"authorization": []byte(token)})
+	_, err := f(ctx, nil, tokenHandler(t, token))
+	assert.Nil(t, err)
+
+	msg.WithServerMetaData(nil)
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Equal(t, errs.RetServerAuthFail, errs.Code(err))
+
+	msg.WithServerRPCName("/trpc.app.server.Greeter/Login")
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+
+	// Non-HTTP requests are skipped without metadata source.
+	_, err = ServerFilter()(trpc.BackgroundContext(), nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+}
+
+func TestServerFilter_HTTPSources(t *testing.T) {
+	token := mockToken(t)
+	f := ServerFilter(WithTokenSources(
+		TokenSource{Type: SourceHeader, Name: "X-Token"},
+		TokenSource{Type: SourceCookie, Name: "token"},
+		TokenSource{Type: SourceQuery, Name: "access_token"},
+	))
+	newCtx := func(r *http.Request) context.Context {
+		return thttp.WithHeader(trpc.BackgroundContext(), &thttp.Header{Request: r})
+	}
+
+	r := &http.Request{URL: &url.URL{Path: "/v1"}, Header: http.Header{}}
+	r.Header.Set("X-Token", token)
+	_, err := f(newCtx(r), nil, tokenHandler(t, token))
+	assert.Nil(t, err)
+
+	r = &http.Request{URL: &url.URL{Path: "/v1"}, Header: http.Header{}}
+	r.AddCookie(&http.Cookie{Name: "token", Value: token})
+	_, err = f(newCtx(r), nil, tokenHandler(t, token))
+	assert.Nil(t, err)
+
+	r = &http.Request{URL: &url.URL{Path: "/v1", RawQuery: "access_token=" + token}, Header: http.Header{}}
+	_, err = f(newCtx(r), nil, tokenHandler(t, token))
+	assert.Nil(t, err)
+
+	// Authorization header is not a configured source.
+	r = &http.Request{URL: &url.URL{Path: "/v1"}, Header: http.Header{}}
+	r.Header.Set("Authorization", "Bearer "+token)
+	_, err = f(newCtx(r), nil, fakeServerHandleFunc)
+	assert.NotNil(t, err)
+}
+
+func TestServerFilter_ServiceTokenSources(t *testing.T) {
+	token := mockToken(t)
+	f := ServerFilter(WithServiceTokenSources(map[string][]TokenSource{
+		"trpc.app.server.Greeter": {{Type: SourceMetadata, Name: "token", Prefix: "Bearer "}},
+	}))
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithCalleeServiceName("trpc.app.server.Greeter")
+	msg.WithServerMetaData(codec.MetaData{"token": []byte("Bearer " + token)})
+	_, err := f(ctx, nil, tokenHandler(t, token))
+	assert.Nil(t, err)
+
+	msg.WithServerMetaData(codec.MetaData{"token": []byte(token)})
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.NotNil(t, err)
+
+	// Other services are not affected.
+	msg.WithCalleeServiceName("trpc.app.server.Other")
+	_, err = f(ctx, nil, fakeServerHandleFunc)
+	assert.Nil(t, err)
+}
+
+func TestClientFilter(t *testing.T) {
+	handler := func(ctx context.Context, req, rsp interface{}) error { return nil }
+
+	// Propagate the token of server.
+	shared := codec.MetaData{"k": []byte("v")}
+	ctx := context.WithValue(trpc.BackgroundContext(), AuthTokenCtxKey, "token")
+	trpc.Message(ctx).WithClientMetaData(shared)
+	assert.Nil(t, ClientFilter()(ctx, nil, nil, handler))
+	assert.Equal(t, "token", string(trpc.Message(ctx).ClientMetaData()["authorization"]))
+	assert.Equal(t, "v", string(trpc.Message(ctx).ClientMetaData()["k"]))
+	assert.NotContains(t, shared, "authorization")
+
+	// No token, nothing injected.
+	ctx = trpc.BackgroundContext()
+	assert.Nil(t, ClientFilter()(ctx, nil, nil, handler))
+	assert.Nil(t, trpc.Message(ctx).ClientMetaData())
+
+	// HTTP header.
+	ctx = trpc.BackgroundContext()
+	head := &thttp.ClientReqHeader{}
+	trpc.Message(ctx).WithClientReqHead(head)
+	f := ClientFilter(WithTokenTarget(DefaultTokenSource), WithTokenFunc(func(context.Context) (string, error) {
+		return "token", nil
+	}))
+	assert.Nil(t, f(ctx, nil, nil, handler))
+	assert.Equal(t, "Bearer token", head.Header.Get("Authorization"))
+
+	// No HTTP head, the token is written to metadata instead.
+	ctx = trpc.BackgroundContext()
+	assert.Nil(t, f(ctx, nil, nil, handler))
+	assert.Nil(t, trpc.Message(ctx).ClientReqHead())
+	assert.Equal(t, "Bearer token", string(trpc.Message(ctx).ClientMetaData()["Authorization"]))
+
+	// Only the allowed callees get the token.
+	f = ClientFilter(WithCallees("trpc.app.trusted.*"))
+	ctx = context.WithValue(trpc.BackgroundContext(), AuthTokenCtxKey, "token")
+	trpc.Message(ctx).WithCalleeServiceName("trpc.app.trusted.Greeter")
+	assert.Nil(t, f(ctx, nil, nil, handler))
+	assert.Equal(t, "token", string(trpc.Message(ctx).ClientMetaData()["authorization"]))
+	ctx = context.WithValue(trpc.BackgroundContext(), AuthTokenCtxKey, "token")
+	trpc.Message(ctx).WithCalleeServiceName("trpc.app.other.Greeter")
+	assert.Nil(t, f(ctx, nil, nil, handler))
+	assert.Nil(t, trpc.Message(ctx).ClientMetaData())
+}
+
+func TestTokenSource_Validate(t *testing.T) {
+	assert.Nil(t, TokenSource{Type: SourceCookie, Name: "token"}.validate(false))
+	assert.NotNil(t, TokenSource{Type: SourceCookie, Name: "token"}.validate(true))
+	assert.NotNil(t, TokenSource{Type: SourceHeader}.validate(false))
+	assert.NotNil(t, TokenSource{Type: "unknown", Name: "token"}.validate(false))
+}
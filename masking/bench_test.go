@@ -0,0 +1,163 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// legacyCheckRecursive 按计划遍历前的实现, 每次遍历都反射检查所有值, 作为基准测试的对照
+func legacyCheckRecursive(original reflect.Value) {
+	switch original.Kind() {
+	case reflect.Ptr:
+		originalValue := original.Elem()
+		if !originalValue.IsValid() {
+			return
+		}
+		legacyCheckRecursive(originalValue)
+
+	case reflect.Interface:
+		if original.IsNil() {
+			return
+		}
+		originalValue := original.Elem()
+		if v, ok := originalValue.Interface().(Masking); ok {
+			v.Masking()
+		}
+		legacyCheckRecursive(originalValue)
+
+	case reflect.Struct:
+		if _, ok := original.Interface().(time.Time); ok {
+			return
+		}
+		for i := 0; i < original.NumField(); i++ {
+			if original.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if v, ok := original.Field(i).Interface().(Masking); ok {
+				v.Masking()
+			}
+			legacyCheckRecursive(original.Field(i))
+		}
+
+	case reflect.Slice:
+		if original.IsNil() {
+			return
+		}
+		for i := 0; i < original.Len(); i++ {
+			if v, ok := original.Index(i).Interface().(Masking); ok {
+				v.Masking()
+			}
+			legacyCheckRecursive(original.Index(i))
+		}
+
+	case reflect.Map:
+		if original.IsNil() {
+			return
+		}
+		for _, key := range original.MapKeys() {
+			legacyCheckRecursive(original.MapIndex(key))
+			if v, ok := key.Interface().(Masking); ok {
+				v.Masking()
+			}
+		}
+	}
+}
+
+type benchItem struct {
+	ID      int64
+	Name    string
+	Price   float64
+	Tags    []string
+	Created time.Time
+	Attrs   map[string]string
+}
+
+type benchUser struct {
+	Phone string
+}
+
+func (u *benchUser) Masking() {
+	u.Phone = keep(u.Phone, 3, 4)
+}
+
+type benchRsp struct {
+	Code  int
+	Msg   string
+	User  *benchUser
+	Items []*benchItem
+}
+
+type benchTaggedRsp struct {
+	Code  int
+	Msg   string
+	Phone string `mask:"phone"`
+	Items []*benchItem
+}
+
+func newBenchItems(n int) []*benchItem {
+	items := make([]*benchItem, n)
+	for i := range items {
+		items[i] = &benchItem{
+			ID:    int64(i),
+			Name:  "item" + strconv.Itoa(i),
+			Tags:  []string{"a", "b", "c"},
+			Attrs: map[string]string{"color": "red", "size": "xl"},
+		}
+	}
+	return items
+}
+
+// BenchmarkLegacy_Masking 原实现处理包含 Masking 方法及大量无需脱敏的数据的响应
+func BenchmarkLegacy_Masking(b *testing.B) {
+	rsp := &benchRsp{User: &benchUser{Phone: "13812345678"}, Items: newBenchItems(100)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyCheckRecursive(reflect.ValueOf(rsp))
+	}
+}
+
+// BenchmarkDeepCheck_Masking 按缓存的计划处理相同的响应, 跳过无需脱敏的子树
+func BenchmarkDeepCheck_Masking(b *testing.B) {
+	rsp := &benchRsp{User: &benchUser{Phone: "13812345678"}, Items: newBenchItems(100)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DeepCheck(rsp)
+	}
+}
+
+// BenchmarkDeepCheck_Tags 按 mask 标签脱敏
+func BenchmarkDeepCheck_Tags(b *testing.B) {
+	rsp := &benchTaggedRsp{Phone: "13812345678", Items: newBenchItems(100)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DeepCheck(rsp)
+	}
+}
+
+// BenchmarkMasker_Rules 按配置规则脱敏, map 的 key 参与规则匹配
+func BenchmarkMasker_Rules(b *testing.B) {
+	m, err := NewMasker(map[string]string{"name": "keep_first=2", "items.attrs.color": "redact"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	rsp := &benchRsp{User: &benchUser{Phone: "13812345678"}, Items: newBenchItems(100)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Mask(rsp)
+	}
+}
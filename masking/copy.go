@@ -0,0 +1,126 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Masked 返回使用默认脱敏器脱敏的深拷贝, 不修改 src, 用于打印日志等场景
+func Masked(src interface{}) interface{} {
+	return DefaultMasker.Copy(src)
+}
+
+// MaskLogFunc 包装打印请求及响应的函数, 打印前先对 req 和 rsp 的深拷贝脱敏, 如
+// debuglog.WithLogFunc(masking.MaskLogFunc(debuglog.JSONLogFunc))
+func MaskLogFunc(
+	f func(ctx context.Context, req, rsp interface{}) string,
+) func(ctx context.Context, req, rsp interface{}) string {
+	return func(ctx context.Context, req, rsp interface{}) string {
+		return f(ctx, Masked(req), Masked(rsp))
+	}
+}
+
+// Copy 返回 src 脱敏后的深拷贝, 不修改 src。与 Mask 不同, 非指针的结构体也会被脱敏。
+// 结构体的非导出字段为浅拷贝。
+func (m *Masker) Copy(src interface{}) interface{} {
+	if src == nil {
+		return nil
+	}
+	if msg, ok := src.(proto.Message); ok {
+		dst := proto.Clone(msg)
+		m.Mask(dst)
+		return dst
+	}
+	original := reflect.ValueOf(src)
+	// 拷贝到可寻址的值上, 以便脱敏
+	dst := reflect.New(original.Type()).Elem()
+	dst.Set(deepCopy(original, make(map[uintptr]reflect.Value)))
+	m.maskValue(dst)
+	return dst.Interface()
+}
+
+// deepCopy 返回 v 的深拷贝, copied 记录已拷贝的指针, 保持共享及循环引用
+func deepCopy(v reflect.Value, copied map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if c, ok := copied[v.Pointer()]; ok && c.Type() == v.Type() {
+			return c
+		}
+		if msg, ok := v.Interface().(proto.Message); ok {
+			c := reflect.ValueOf(proto.Clone(msg))
+			copied[v.Pointer()] = c
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		copied[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), copied))
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), copied))
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			c.Field(i).Set(deepCopy(v.Field(i), copied))
+		}
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, deepCopy(v.MapIndex(key), copied))
+		}
+		return c
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,88 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"trpc.group/trpc-go/trpc-filter/masking/internal/testpb"
+)
+
+type copyNode struct {
+	Phone string `mask:"phone"`
+	At    time.Time
+	Next  *copyNode
+	Other *copyNode
+	Arr   [2]string `mask:"redact"`
+	Data  interface{}
+	inner string
+}
+
+func TestMasker_Copy(t *testing.T) {
+	src := &copyNode{
+		Phone: "13812345678",
+		Arr:   [2]string{"a", "b"},
+		Data:  map[string]interface{}{"phone": "13812345678", "list": []interface{}{"x"}},
+		inner: "inner",
+	}
+	src.Next = &copyNode{Phone: "13912345678"}
+	src.Other = src.Next
+	m, err := NewMasker(map[string]string{"data.phone": "phone"})
+	assert.Nil(t, err)
+	dst := m.Copy(src).(*copyNode)
+	assert.Equal(t, "13812345678", src.Phone)
+	assert.Equal(t, [2]string{"a", "b"}, src.Arr)
+	assert.Equal(t, "13812345678", src.Data.(map[string]interface{})["phone"])
+	assert.Equal(t, "138****5678", dst.Phone)
+	assert.Equal(t, [2]string{"******", "******"}, dst.Arr)
+	assert.Equal(t, "138****5678", dst.Data.(map[string]interface{})["phone"])
+	assert.Equal(t, "inner", dst.inner)
+	assert.Equal(t, "13912345678", src.Next.Phone)
+	assert.Equal(t, "139****5678", dst.Next.Phone)
+	assert.True(t, dst.Next == dst.Other)
+
+	// Struct values are masked too.
+	v := m.Copy(copyNode{Phone: "13812345678"}).(copyNode)
+	assert.Equal(t, "138****5678", v.Phone)
+	assert.Nil(t, m.Copy(nil))
+	assert.Equal(t, "abc", m.Copy("abc"))
+}
+
+func TestMasker_CopyProto(t *testing.T) {
+	src := newProtoUser()
+	dst := Masked(src).(*testpb.User)
+	assert.True(t, proto.Equal(newProtoUser(), src))
+	assert.Equal(t, "138****5678", dst.Phone)
+
+	wrapped := Masked(&struct{ User *testpb.User }{User: src}).(*struct{ User *testpb.User })
+	assert.True(t, proto.Equal(newProtoUser(), src))
+	assert.Equal(t, "138****5678", wrapped.User.Phone)
+}
+
+func TestMaskLogFunc(t *testing.T) {
+	req := &copyNode{Phone: "13812345678"}
+	rsp := newProtoUser()
+	f := MaskLogFunc(func(ctx context.Context, req, rsp interface{}) string {
+		return fmt.Sprintf("req: %s, rsp: %s", req.(*copyNode).Phone, rsp.(*testpb.User).GetPhone())
+	})
+	assert.Equal(t, "req: 138****5678, rsp: 138****5678", f(context.Background(), req, rsp))
+	assert.Equal(t, "13812345678", req.Phone)
+	assert.Equal(t, "13812345678", rsp.Phone)
+}
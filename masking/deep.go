@@ -15,79 +15,262 @@
 package masking
 
 import (
+	"fmt"
 	"reflect"
-	"time"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
 )
 
-// DeepCheck 递归处理待脱敏数据
-func DeepCheck(src interface{}) {
-	if src == nil {
-		return
-	}
+// tagName 脱敏标签名, 如 `mask:"phone"`, `mask:"keep_last=4"`, `mask:"-"` 表示不脱敏
+const tagName = "mask"
 
-	original := reflect.ValueOf(src)
+// DefaultMasker 默认的脱敏器, 只处理 Masking 方法及 mask 标签
+var DefaultMasker = &Masker{}
 
-	checkRecursive(original)
+// SetDefaultMasker 设置默认脱敏器
+func SetDefaultMasker(m *Masker) {
+	if m != nil {
+		DefaultMasker = m
+	}
 }
 
-// checkRecursive 调用masking插件导出的脱敏方法
-func checkRecursive(original reflect.Value) {
+// Masker 脱敏器, 调用 Masking 方法, 并按 mask 标签及配置规则对字符串字段脱敏。
+// 每个类型的遍历计划只编译一次并缓存, 遍历时跳过不包含需要处理的值的子树, 并跳过已访问的指针以支持循环引用。
+type Masker struct {
+	rules   []rule          // 按路径长度降序
+	exempts []rule          // 不脱敏的字段, 优先于标签及配置规则
+	depth   int             // rules 及 exempts 路径的最大长度
+	names   map[string]bool // rules 及 exempts 路径中的字段名
 
-	switch original.Kind() {
-	case reflect.Ptr:
-		originalValue := original.Elem()
+	mu          sync.Mutex // 编译遍历计划
+	plans       sync.Map   // map[planKey]*plan
+	exemptCache sync.Map   // map[string]*Masker, 放开部分字段的脱敏器
+}
 
-		if !originalValue.IsValid() {
-			return
-		}
-		checkRecursive(originalValue)
+// rule 配置规则
+type rule struct {
+	path     []string // 规范化的字段名
+	strategy *maskStrategy
+}
 
-	case reflect.Interface:
-		if original.IsNil() {
-			return
+// NewMasker 构造脱敏器, rules 为字段名或以 . 分隔的字段路径 -> 策略, 如 phone_num: phone, user.id: keep_last=4。
+// 字段名为 json 标签名(没有时为结构体字段名)或 map 的 key, 不区分大小写且忽略下划线;
+// 路径匹配字段路径的末尾部分, 有多个规则匹配时使用最长的路径, mask 标签优先于配置规则。
+func NewMasker(rules map[string]string) (*Masker, error) {
+	m := &Masker{}
+	for path, spec := range rules {
+		s, err := parseStrategy(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%w, rule: %s", err, path)
 		}
-		originalValue := original.Elem()
-		if v, ok := originalValue.Interface().(Masking); ok {
-			v.Masking()
-		}
-		checkRecursive(originalValue)
+		m.rules = append(m.rules, rule{path: splitPath(path), strategy: s})
+	}
+	sort.SliceStable(m.rules, func(i, j int) bool {
+		return len(m.rules[i].path) > len(m.rules[j].path)
+	})
+	m.depth = maxDepth(m.rules)
+	m.names = pathNames(m.rules)
+	return m, nil
+}
+
+// exempt 返回不对 fields 脱敏的脱敏器, fields 为字段名或以 . 分隔的字段路径
+func (m *Masker) exempt(fields []string) *Masker {
+	key := strings.Join(fields, ",")
+	if e, ok := m.exemptCache.Load(key); ok {
+		return e.(*Masker)
+	}
+	e := &Masker{rules: m.rules, exempts: append([]rule(nil), m.exempts...)}
+	for _, field := range fields {
+		e.exempts = append(e.exempts, rule{path: splitPath(field)})
+	}
+	e.depth = maxDepth(e.rules)
+	if d := maxDepth(e.exempts); d > e.depth {
+		e.depth = d
+	}
+	e.names = pathNames(append(append([]rule(nil), e.rules...), e.exempts...))
+	v, _ := m.exemptCache.LoadOrStore(key, e)
+	return v.(*Masker)
+}
 
-	case reflect.Struct:
-		_, ok := original.Interface().(time.Time)
-		if ok {
-			return
+func maxDepth(rules []rule) int {
+	var depth int
+	for _, r := range rules {
+		if len(r.path) > depth {
+			depth = len(r.path)
 		}
-		for i := 0; i < original.NumField(); i++ {
-			if original.Type().Field(i).PkgPath != "" {
-				continue
-			}
-			if v, ok := original.Field(i).Interface().(Masking); ok {
-				v.Masking()
-			}
-			checkRecursive(original.Field(i))
+	}
+	return depth
+}
+
+func pathNames(rules []rule) map[string]bool {
+	names := make(map[string]bool)
+	for _, r := range rules {
+		for _, name := range r.path {
+			names[name] = true
 		}
+	}
+	return names
+}
 
-	case reflect.Slice:
-		if original.IsNil() {
-			return
+// exempted path 是否为不脱敏的字段
+func (m *Masker) exempted(path []string) bool {
+	for _, r := range m.exempts {
+		if hasSuffix(path, r.path) {
+			return true
 		}
-		for i := 0; i < original.Len(); i++ {
-			if v, ok := original.Index(i).Interface().(Masking); ok {
-				v.Masking()
-			}
-			checkRecursive(original.Index(i))
+	}
+	return false
+}
+
+// matchRule 返回匹配 path 的规则的策略, 不脱敏的字段返回 nil, 没有匹配时 ok 为 false
+func (m *Masker) matchRule(path []string) (s *maskStrategy, ok bool) {
+	if m.exempted(path) {
+		return nil, true
+	}
+	for _, r := range m.rules {
+		if hasSuffix(path, r.path) {
+			return r.strategy, true
 		}
+	}
+	return nil, false
+}
 
-	case reflect.Map:
-		if original.IsNil() {
-			return
+// match 返回匹配 path 的最长规则的策略, 没有匹配时返回 inherited
+func (m *Masker) match(path []string, inherited *maskStrategy) *maskStrategy {
+	if s, ok := m.matchRule(path); ok {
+		return s
+	}
+	return inherited
+}
+
+// fieldStrategy 返回字段的脱敏策略, mask 标签优先于配置规则, 都没有时继承上层的策略
+func (m *Masker) fieldStrategy(field reflect.StructField, path []string, inherited *maskStrategy) *maskStrategy {
+	tag, ok := field.Tag.Lookup(tagName)
+	if !ok || tag == "" {
+		return m.match(path, inherited)
+	}
+	if tag == "-" || m.exempted(path) {
+		return nil
+	}
+	s, err := parseStrategy(tag)
+	if err != nil {
+		// 标签错误时全部替换, 避免泄露敏感信息
+		return redactStrategy
+	}
+	return s
+}
+
+// context 返回 path 末尾影响子字段规则匹配的部分, 不在规则中的字段名不会被匹配, 替换为空字符串,
+// 以免 map 的 key 等来自数据的字段名产生无限多的遍历计划
+func (m *Masker) context(path []string) []string {
+	n := m.depth - 1
+	if n <= 0 {
+		return nil
+	}
+	if len(path) > n {
+		path = path[len(path)-n:]
+	}
+	context := make([]string, len(path))
+	for i, name := range path {
+		if m.names[name] {
+			context[i] = name
 		}
-		for _, key := range original.MapKeys() {
-			originalValue := original.MapIndex(key)
-			checkRecursive(originalValue)
-			if v, ok := key.Interface().(Masking); ok {
-				v.Masking()
-			}
+	}
+	return context
+}
+
+// DeepCheck 递归处理待脱敏数据
+func DeepCheck(src interface{}) {
+	DefaultMasker.Mask(src)
+}
+
+// Mask 递归处理待脱敏数据, 只能修改可寻址的值, 如指针指向的结构体。
+// protobuf 消息按 protobuf 反射遍历, 并使用字段的 (trpc.mask) 选项, 见 maskpb/mask.proto。
+func (m *Masker) Mask(src interface{}) {
+	if src == nil {
+		return
+	}
+	if msg, ok := src.(proto.Message); ok {
+		m.maskMessage(msg.ProtoReflect(), nil, nil)
+		return
+	}
+	m.maskValue(reflect.ValueOf(src))
+}
+
+// maskValue 按遍历计划处理 v
+func (m *Masker) maskValue(v reflect.Value) {
+	w := walker{m: m}
+	w.walk(v, m.plan(v.Type(), nil, nil))
+}
+
+// hasSuffix path 的末尾是否为 suffix
+func hasSuffix(path, suffix []string) bool {
+	if len(suffix) > len(path) {
+		return false
+	}
+	offset := len(path) - len(suffix)
+	for i, name := range suffix {
+		if path[offset+i] != name {
+			return false
 		}
 	}
+	return true
+}
+
+// splitPath 将以 . 分隔的字段路径拆分为规范化的字段名
+func splitPath(path string) []string {
+	var segments []string
+	for _, name := range strings.Split(path, ".") {
+		segments = append(segments, normalize(name))
+	}
+	return segments
+}
+
+// appendPath 返回追加字段名后的新路径, 不修改 path
+func appendPath(path []string, name string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, normalize(name))
+}
+
+// fieldName 返回字段的 json 标签名, 没有时返回结构体字段名
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// normalize 规范化字段名, 转换为小写并去掉下划线
+func normalize(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "")
+}
+
+// callMasking 调用 v 实现的 Masking 方法, 跳过 nil 指针
+func callMasking(v reflect.Value) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return
+	}
+	if m, ok := v.Interface().(Masking); ok {
+		m.Masking()
+	}
+}
+
+// stringValue 返回 v 或 v 中接口值的字符串, 不是字符串时返回无效值
+func stringValue(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// maskString 返回字符串 v 脱敏后的值, 保持原有类型
+func maskString(v reflect.Value, s *maskStrategy) reflect.Value {
+	return reflect.ValueOf(s.fn(v.String())).Convert(v.Type())
 }
@@ -0,0 +1,111 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type maskedPhone string
+
+type maskedUser struct {
+	Name     string            `json:"name"`
+	Phone    string            `mask:"phone"`
+	Email    *string           `json:"email,omitempty" mask:"email"`
+	Card     maskedPhone       `mask:"keep_last=4"`
+	Tags     []string          `mask:"redact"`
+	Extra    interface{}       `mask:"redact"`
+	Bad      string            `mask:"unknown"`
+	Plain    string            `mask:"-"`
+	IDCard   string            `json:"id_card"`
+	Contacts map[string]string `json:"contacts"`
+	Address  *maskedAddress
+	Children []*maskedUser
+	Props    map[string]interface{}
+}
+
+type maskedAddress struct {
+	Detail string
+	Phone  string
+}
+
+func TestMasker_Tags(t *testing.T) {
+	email := "alice@example.com"
+	u := &maskedUser{
+		Name:  "alice",
+		Phone: "13812345678",
+		Email: &email,
+		Card:  "6222021234",
+		Tags:  []string{"a", "b"},
+		Extra: "extra",
+		Bad:   "bad",
+		Plain: "plain",
+	}
+	DeepCheck(u)
+	assert.Equal(t, "alice", u.Name)
+	assert.Equal(t, "138****5678", u.Phone)
+	assert.Equal(t, "a****@example.com", email)
+	assert.Equal(t, maskedPhone("******1234"), u.Card)
+	assert.Equal(t, []string{"******", "******"}, u.Tags)
+	assert.Equal(t, "******", u.Extra)
+	assert.Equal(t, "******", u.Bad)
+	assert.Equal(t, "plain", u.Plain)
+}
+
+func TestMasker_Rules(t *testing.T) {
+	SetHashKey([]byte("key"))
+	defer SetHashKey(nil)
+	m, err := NewMasker(map[string]string{
+		"id_card":         "id_card",
+		"Phone":           "phone",
+		"address.phone":   "keep_last=2",
+		"address.detail":  "redact",
+		"contacts":        "keep_first=1",
+		"props.secret":    "hash",
+		"plain":           "redact",
+		"children.idcard": "redact",
+	})
+	assert.Nil(t, err)
+	u := &maskedUser{
+		Phone:    "13812345678",
+		Plain:    "plain",
+		IDCard:   "110101199003071234",
+		Contacts: map[string]string{"mom": "13800000000"},
+		Address:  &maskedAddress{Detail: "road", Phone: "13812345678"},
+		Children: []*maskedUser{{IDCard: "110101199003071234"}},
+		Props:    map[string]interface{}{"secret": "abc", "public": "abc", "nested": map[string]interface{}{"phone": "13812345678"}},
+	}
+	m.Mask(u)
+	assert.Equal(t, "138****5678", u.Phone)
+	assert.Equal(t, "plain", u.Plain)
+	assert.Equal(t, "110***********1234", u.IDCard)
+	assert.Equal(t, map[string]string{"mom": "1**********"}, u.Contacts)
+	assert.Equal(t, &maskedAddress{Detail: "******", Phone: "*********78"}, u.Address)
+	assert.Equal(t, "******", u.Children[0].IDCard)
+	assert.Equal(t, "9c196e32dc0175f86f4b1cb89289d6619de6bee699e4c378e68309ed97a1a6ab", u.Props["secret"])
+	assert.Equal(t, "abc", u.Props["public"])
+	assert.Equal(t, "138****5678", u.Props["nested"].(map[string]interface{})["phone"])
+
+	_, err = NewMasker(map[string]string{"phone": "unknown"})
+	assert.NotNil(t, err)
+}
+
+func TestMasker_NotAddressable(t *testing.T) {
+	u := maskedUser{Phone: "13812345678"}
+	DeepCheck(u)
+	assert.Equal(t, "13812345678", u.Phone)
+	DeepCheck(nil)
+}
@@ -21,7 +21,7 @@ import (
 )
 
 func init() {
-	filter.Register(pluginName, ServerFilter(), nil)
+	filter.Register(pluginName, ServerFilter(), ClientFilter())
 }
 
 // Masking 脱敏接口
@@ -29,14 +29,81 @@ type Masking interface {
 	Masking()
 }
 
-// ServerFilter 服务端RPC调用自动校验req输入参数
-func ServerFilter() filter.ServerFilter {
+// options 拦截器选项
+type options struct {
+	masker *Masker        // 脱敏器, 为空时使用 DefaultMasker
+	copy   bool           // 是否返回脱敏的深拷贝, 不修改原响应
+	unmask unmaskPolicies // 放开脱敏的策略, 只用于服务端
+}
+
+// mask 对 rsp 脱敏, 返回脱敏后的响应
+func (o *options) mask(ctx context.Context, rsp interface{}) interface{} {
+	m := o.masker
+	if m == nil {
+		m = DefaultMasker
+	}
+	if m = o.unmask.masker(ctx, m); m == nil {
+		return rsp
+	}
+	if o.copy {
+		return m.Copy(rsp)
+	}
+	m.Mask(rsp)
+	return rsp
+}
+
+// Option 拦截器选项
+type Option func(*options)
+
+// WithMasker 设置脱敏器, 未设置时使用 DefaultMasker
+func WithMasker(m *Masker) Option {
+	return func(o *options) {
+		o.masker = m
+	}
+}
+
+// WithCopy 设置服务端拦截器返回脱敏的深拷贝, 不修改 handler 返回的响应, 如响应来自共享的缓存
+func WithCopy(copy bool) Option {
+	return func(o *options) {
+		o.copy = copy
+	}
+}
+
+// WithUnmaskPolicies 设置服务端放开脱敏的策略, 按顺序使用第一个满足的策略, 并记录审计日志。
+// 没有配置 callers, metadata 或 claims 条件的策略不生效。
+func WithUnmaskPolicies(policies ...UnmaskPolicy) Option {
+	return func(o *options) {
+		o.unmask = policies
+	}
+}
+
+// ServerFilter 服务端RPC调用自动对rsp响应参数值做脱敏
+func ServerFilter(opts ...Option) filter.ServerFilter {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
 	return func(ctx context.Context, req interface{}, handler filter.ServerHandleFunc) (rsp interface{}, err error) {
 		rsp, err = handler(ctx, req)
 		if err != nil {
 			return nil, err
 		}
-		DeepCheck(rsp)
-		return rsp, nil
+		return o.mask(ctx, rsp), nil
+	}
+}
+
+// ClientFilter 客户端RPC调用自动对下游的rsp响应参数值做脱敏, rsp 由调用方传入, 总是原地脱敏
+func ClientFilter(opts ...Option) filter.ClientFilter {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.copy, o.unmask = false, nil
+	return func(ctx context.Context, req, rsp interface{}, handler filter.ClientHandleFunc) error {
+		if err := handler(ctx, req, rsp); err != nil {
+			return err
+		}
+		o.mask(ctx, rsp)
+		return nil
 	}
 }
@@ -30,6 +30,18 @@ func init() {
 	plugin.Register(pluginName, &MaskingPlugin{})
 }
 
+// Config 插件配置
+type Config struct {
+	// Rules 字段名或以 . 分隔的字段路径 -> 脱敏策略, 如 phone_num: phone, user.card: keep_last=4
+	Rules map[string]string `yaml:"rules"`
+	// HashKey hash 策略的 HMAC-SHA256 密钥, 未设置时不能使用 hash 策略
+	HashKey string `yaml:"hash_key"`
+	// Copy 服务端拦截器返回脱敏的深拷贝, 不修改 handler 返回的响应
+	Copy bool `yaml:"copy"`
+	// Unmask 服务端放开脱敏的策略, 如客服工具等内部调用方可以查看原始数据
+	Unmask []UnmaskPolicy `yaml:"unmask"`
+}
+
 // MaskingPlugin masking trpc 插件实现
 type MaskingPlugin struct {
 }
@@ -46,14 +58,25 @@ func (p *MaskingPlugin) Setup(_ string, configDec plugin.Decoder) error {
 	if configDec == nil {
 		return errors.New("masking decoder empty")
 	}
-	conf := make(map[string][]string)
+	conf := Config{}
 	err := configDec.Decode(&conf)
 	if err != nil {
 		return err
 	}
+	if conf.HashKey != "" {
+		SetHashKey([]byte(conf.HashKey))
+	}
+	m, err := NewMasker(conf.Rules)
+	if err != nil {
+		return err
+	}
+	if err := unmaskPolicies(conf.Unmask).validate(); err != nil {
+		return err
+	}
+	SetDefaultMasker(m)
 
-	sf := ServerFilter()
+	sf := ServerFilter(WithCopy(conf.Copy), WithUnmaskPolicies(conf.Unmask...))
 
-	filter.Register(pluginName, sf, nil)
+	filter.Register(pluginName, sf, ClientFilter())
 	return nil
 }
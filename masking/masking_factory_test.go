@@ -14,6 +14,7 @@
 package masking
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,6 +32,38 @@ plugins:
     validation:
 `
 
+const confRules = `
+plugins:
+  auth:
+    masking:
+      rules:
+        phone_num: phone
+        user.card: keep_last=4
+      unmask:
+        - name: customer-service
+          methods: ["/trpc.app.user.User/*"]
+          callers: [trpc.app.cs.Tool]
+          claims:
+            roles: cs
+`
+
+const confInvalidUnmask = `
+plugins:
+  auth:
+    masking:
+      unmask:
+        - name: all
+          methods: ["/trpc.app.user.User/*"]
+`
+
+const confInvalidRule = `
+plugins:
+  auth:
+    masking:
+      rules:
+        phone_num: unknown
+`
+
 func readConf(conf string) plugin.Decoder {
 	cfg := trpc.Config{}
 	err := yaml.Unmarshal([]byte(conf), &cfg)
@@ -58,10 +91,14 @@ func TestPlugin_Setup(t *testing.T) {
 		wantErr bool
 	}{
 		{"test succ no logfile", &MaskingPlugin{}, args{name: pluginName, configDec: readConf(confNoLogfile)}, false},
+		{"test succ rules", &MaskingPlugin{}, args{name: pluginName, configDec: readConf(confRules)}, false},
+		{"test err invalid unmask", &MaskingPlugin{}, args{name: pluginName, configDec: readConf(confInvalidUnmask)}, true},
+		{"test err invalid rule", &MaskingPlugin{}, args{name: pluginName, configDec: readConf(confInvalidRule)}, true},
 		{"test err configDec nil", &MaskingPlugin{}, args{name: pluginName, configDec: nil}, true},
 		{"test err configDec decode error", &MaskingPlugin{}, args{name: pluginName,
 			configDec: &plugin.YamlNodeDecoder{}}, true},
 	}
+	SetClaimsFunc(func(ctx context.Context) map[string]interface{} { return nil })
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &MaskingPlugin{}
@@ -69,4 +106,8 @@ func TestPlugin_Setup(t *testing.T) {
 			assert.Equal(t, err != nil, tt.wantErr)
 		})
 	}
+
+	// claims 条件需要先设置 ClaimsFunc
+	SetClaimsFunc(nil)
+	assert.NotNil(t, (&MaskingPlugin{}).Setup(pluginName, readConf(confRules)))
 }
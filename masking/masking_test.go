@@ -15,6 +15,7 @@ package masking
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -80,3 +81,29 @@ func TestServerFilter(t *testing.T) {
 	_, err = filter(context.TODO(), nil, handler)
 	assert.Nil(t, err)
 }
+
+func TestServerFilter_Copy(t *testing.T) {
+	cached := &PbData{Data: &MockRspData{Uid: "123"}}
+	filter := ServerFilter(WithCopy(true))
+	rsp, err := filter(context.TODO(), cached, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "masking_uid", rsp.(*PbData).Data.Uid)
+	assert.Equal(t, "123", cached.Data.Uid)
+}
+
+func TestClientFilter(t *testing.T) {
+	filter := ClientFilter(WithMasker(&Masker{}), WithCopy(true))
+	rsp := &PbData{Data: &MockRspData{Uid: "123"}}
+	err := filter(context.TODO(), nil, rsp, func(ctx context.Context, req, rsp interface{}) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "masking_uid", rsp.Data.Uid)
+
+	rsp = &PbData{Data: &MockRspData{Uid: "123"}}
+	err = filter(context.TODO(), nil, rsp, func(ctx context.Context, req, rsp interface{}) error {
+		return errors.New("downstream error")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "123", rsp.Data.Uid)
+}
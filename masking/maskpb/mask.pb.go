@@ -0,0 +1,212 @@
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        v3.21.12
+// source: maskpb/mask.proto
+
+package maskpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MaskType 内置脱敏策略
+type MaskType int32
+
+const (
+	// 不脱敏, 也不匹配配置规则
+	MaskType_NONE MaskType = 0
+	// 手机号, 保留前 3 位及后 4 位
+	MaskType_PHONE MaskType = 1
+	// 邮箱, 保留用户名首字符及域名
+	MaskType_EMAIL MaskType = 2
+	// 身份证号, 保留前 3 位及后 4 位
+	MaskType_ID_CARD MaskType = 3
+	// 银行卡号, 保留前 6 位及后 4 位
+	MaskType_BANK_CARD MaskType = 4
+	// 全部替换为 ******
+	MaskType_REDACT MaskType = 5
+	// 替换为 HMAC-SHA256 十六进制摘要, 未设置密钥时全部替换
+	MaskType_HASH MaskType = 6
+)
+
+// Enum value maps for MaskType.
+var (
+	MaskType_name = map[int32]string{
+		0: "NONE",
+		1: "PHONE",
+		2: "EMAIL",
+		3: "ID_CARD",
+		4: "BANK_CARD",
+		5: "REDACT",
+		6: "HASH",
+	}
+	MaskType_value = map[string]int32{
+		"NONE":      0,
+		"PHONE":     1,
+		"EMAIL":     2,
+		"ID_CARD":   3,
+		"BANK_CARD": 4,
+		"REDACT":    5,
+		"HASH":      6,
+	}
+)
+
+func (x MaskType) Enum() *MaskType {
+	p := new(MaskType)
+	*p = x
+	return p
+}
+
+func (x MaskType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MaskType) Descriptor() protoreflect.EnumDescriptor {
+	return file_maskpb_mask_proto_enumTypes[0].Descriptor()
+}
+
+func (MaskType) Type() protoreflect.EnumType {
+	return &file_maskpb_mask_proto_enumTypes[0]
+}
+
+func (x MaskType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MaskType.Descriptor instead.
+func (MaskType) EnumDescriptor() ([]byte, []int) {
+	return file_maskpb_mask_proto_rawDescGZIP(), []int{0}
+}
+
+var file_maskpb_mask_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*MaskType)(nil),
+		Field:         51001,
+		Name:          "trpc.mask",
+		Tag:           "varint,51001,opt,name=mask,enum=trpc.MaskType",
+		Filename:      "maskpb/mask.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         51002,
+		Name:          "trpc.mask_strategy",
+		Tag:           "bytes,51002,opt,name=mask_strategy",
+		Filename:      "maskpb/mask.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// 字段的内置脱敏策略, 如 string phone = 1 [(trpc.mask) = PHONE];
+	//
+	// optional trpc.MaskType mask = 51001;
+	E_Mask = &file_maskpb_mask_proto_extTypes[0]
+	// 字段的脱敏策略, 格式与 mask 标签相同, 如 string card = 2 [(trpc.mask_strategy) = "keep_last=4"];
+	//
+	// optional string mask_strategy = 51002;
+	E_MaskStrategy = &file_maskpb_mask_proto_extTypes[1]
+)
+
+var File_maskpb_mask_proto protoreflect.FileDescriptor
+
+var file_maskpb_mask_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x6d, 0x61, 0x73, 0x6b, 0x70, 0x62, 0x2f, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x04, 0x74, 0x72, 0x70, 0x63, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72,
+	0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2a, 0x5c, 0x0a, 0x08, 0x4d,
+	0x61, 0x73, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10,
+	0x00, 0x12, 0x09, 0x0a, 0x05, 0x50, 0x48, 0x4f, 0x4e, 0x45, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05,
+	0x45, 0x4d, 0x41, 0x49, 0x4c, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x44, 0x5f, 0x43, 0x41,
+	0x52, 0x44, 0x10, 0x03, 0x12, 0x0d, 0x0a, 0x09, 0x42, 0x41, 0x4e, 0x4b, 0x5f, 0x43, 0x41, 0x52,
+	0x44, 0x10, 0x04, 0x12, 0x0a, 0x0a, 0x06, 0x52, 0x45, 0x44, 0x41, 0x43, 0x54, 0x10, 0x05, 0x12,
+	0x08, 0x0a, 0x04, 0x48, 0x41, 0x53, 0x48, 0x10, 0x06, 0x3a, 0x43, 0x0a, 0x04, 0x6d, 0x61, 0x73,
+	0x6b, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x18, 0xb9, 0x8e, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x70, 0x63, 0x2e,
+	0x4d, 0x61, 0x73, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x6d, 0x61, 0x73, 0x6b, 0x3a, 0x44,
+	0x0a, 0x0d, 0x6d, 0x61, 0x73, 0x6b, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12,
+	0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xba,
+	0x8e, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6d, 0x61, 0x73, 0x6b, 0x53, 0x74, 0x72, 0x61,
+	0x74, 0x65, 0x67, 0x79, 0x42, 0x2f, 0x5a, 0x2d, 0x74, 0x72, 0x70, 0x63, 0x2e, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x2f, 0x74, 0x72, 0x70, 0x63, 0x2d, 0x67, 0x6f, 0x2f, 0x74, 0x72, 0x70, 0x63, 0x2d,
+	0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x2f, 0x6d, 0x61, 0x73, 0x6b, 0x69, 0x6e, 0x67, 0x2f, 0x6d,
+	0x61, 0x73, 0x6b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_maskpb_mask_proto_rawDescOnce sync.Once
+	file_maskpb_mask_proto_rawDescData = file_maskpb_mask_proto_rawDesc
+)
+
+func file_maskpb_mask_proto_rawDescGZIP() []byte {
+	file_maskpb_mask_proto_rawDescOnce.Do(func() {
+		file_maskpb_mask_proto_rawDescData = protoimpl.X.CompressGZIP(file_maskpb_mask_proto_rawDescData)
+	})
+	return file_maskpb_mask_proto_rawDescData
+}
+
+var file_maskpb_mask_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_maskpb_mask_proto_goTypes = []interface{}{
+	(MaskType)(0),                     // 0: trpc.MaskType
+	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+}
+var file_maskpb_mask_proto_depIdxs = []int32{
+	1, // 0: trpc.mask:extendee -> google.protobuf.FieldOptions
+	1, // 1: trpc.mask_strategy:extendee -> google.protobuf.FieldOptions
+	0, // 2: trpc.mask:type_name -> trpc.MaskType
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	2, // [2:3] is the sub-list for extension type_name
+	0, // [0:2] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_maskpb_mask_proto_init() }
+func file_maskpb_mask_proto_init() {
+	if File_maskpb_mask_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_maskpb_mask_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 2,
+			NumServices:   0,
+		},
+		GoTypes:           file_maskpb_mask_proto_goTypes,
+		DependencyIndexes: file_maskpb_mask_proto_depIdxs,
+		EnumInfos:         file_maskpb_mask_proto_enumTypes,
+		ExtensionInfos:    file_maskpb_mask_proto_extTypes,
+	}.Build()
+	File_maskpb_mask_proto = out.File
+	file_maskpb_mask_proto_rawDesc = nil
+	file_maskpb_mask_proto_goTypes = nil
+	file_maskpb_mask_proto_depIdxs = nil
+}
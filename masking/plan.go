@@ -0,0 +1,357 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	maskingType = reflect.TypeOf((*Masking)(nil)).Elem()
+	messageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// planOp 遍历计划的操作
+type planOp uint8
+
+const (
+	opSkip      planOp = iota // 子树中没有需要处理的值
+	opString                  // 按策略替换字符串
+	opPtr                     // 处理指针指向的值
+	opProto                   // 按 protobuf 反射处理消息
+	opInterface               // 按动态类型的计划处理接口值
+	opStruct                  // 处理结构体字段
+	opList                    // 处理 slice 及数组元素
+	opMap                     // 处理 map 的 key 及值
+)
+
+// planKey 遍历计划的缓存键, 同一类型在不同路径及继承的策略下计划不同
+type planKey struct {
+	typ       reflect.Type
+	context   string // 路径末尾影响规则匹配的部分
+	inherited *maskStrategy
+}
+
+// plan 类型的遍历计划
+type plan struct {
+	op       planOp
+	typ      reflect.Type
+	strategy *maskStrategy // 字符串及接口值使用的策略, 也是 map 值继承的策略
+	context  []string      // 路径末尾影响规则匹配的部分, 用于接口值及 map 值的动态查找
+
+	elem        *plan       // 指针, 列表及 map 的元素的计划, 为空时按 map 的 key 查找
+	elemMasking bool        // 列表元素类型实现了 Masking
+	keyMasking  bool        // map 的 key 类型实现了 Masking
+	fields      []fieldPlan // 需要处理的结构体字段
+}
+
+// fieldPlan 结构体字段的遍历计划
+type fieldPlan struct {
+	index   int
+	masking bool // 字段类型实现了 Masking
+	plan    *plan
+}
+
+// plan 返回类型 t 在路径 context 及继承的策略 s 下的遍历计划, 没有时编译并缓存
+func (m *Masker) plan(t reflect.Type, context []string, s *maskStrategy) *plan {
+	key := planKey{typ: t, context: strings.Join(context, "\x00"), inherited: s}
+	if p, ok := m.plans.Load(key); ok {
+		return p.(*plan)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	building := make(map[planKey]*plan)
+	p := m.compile(key, context, building)
+	prune(building)
+	// 编译完成后再发布, 避免并发的遍历使用未完成的计划
+	for k, v := range building {
+		m.plans.Store(k, v)
+	}
+	return p
+}
+
+// compile 编译遍历计划, building 记录本次编译的计划, 递归类型引用编译中的计划
+func (m *Masker) compile(key planKey, context []string, building map[planKey]*plan) *plan {
+	if p, ok := m.plans.Load(key); ok {
+		return p.(*plan)
+	}
+	if p, ok := building[key]; ok {
+		return p
+	}
+	t, s := key.typ, key.inherited
+	p := &plan{typ: t, strategy: s, context: context}
+	building[key] = p
+	child := func(t reflect.Type, context []string, s *maskStrategy) *plan {
+		k := planKey{typ: t, context: strings.Join(context, "\x00"), inherited: s}
+		return m.compile(k, context, building)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if s != nil {
+			p.op = opString
+		}
+
+	case reflect.Ptr:
+		if t.Implements(messageType) {
+			p.op = opProto
+			break
+		}
+		p.op = opPtr
+		p.elem = child(t.Elem(), context, s)
+
+	case reflect.Interface:
+		p.op = opInterface
+
+	case reflect.Struct:
+		if t == timeType {
+			break
+		}
+		p.op = opStruct
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			path := appendPath(context, fieldName(field))
+			p.fields = append(p.fields, fieldPlan{
+				index:   i,
+				masking: implementsMasking(field.Type),
+				plan:    child(field.Type, m.context(path), m.fieldStrategy(field, path, s)),
+			})
+		}
+
+	case reflect.Slice, reflect.Array:
+		p.op = opList
+		p.elem = child(t.Elem(), context, s)
+		p.elemMasking = implementsMasking(t.Elem())
+
+	case reflect.Map:
+		p.op = opMap
+		p.keyMasking = implementsMasking(t.Key())
+		// string key 参与规则匹配时, 遍历时按 key 查找值的计划
+		if t.Key().Kind() != reflect.String || m.depth == 0 {
+			p.elem = child(t.Elem(), context, s)
+		}
+	}
+	return p
+}
+
+// prune 将本次编译的计划中不包含需要处理的值的子树标记为跳过。
+// 递归类型的计划相互引用, 从确定需要处理的计划开始迭代, 直到没有新的计划需要处理。
+func prune(building map[planKey]*plan) {
+	needed := make(map[*plan]bool, len(building))
+	compiled := make(map[*plan]bool, len(building))
+	for _, p := range building {
+		compiled[p] = true
+	}
+	need := func(p *plan) bool {
+		if compiled[p] {
+			return needed[p]
+		}
+		return p.op != opSkip
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range building {
+			if !needed[p] && p.needs(need) {
+				needed[p] = true
+				changed = true
+			}
+		}
+	}
+	for _, p := range building {
+		if !needed[p] {
+			p.op, p.elem, p.fields = opSkip, nil, nil
+			continue
+		}
+		if p.op != opStruct {
+			continue
+		}
+		fields := p.fields[:0]
+		for _, f := range p.fields {
+			if f.masking || need(f.plan) {
+				fields = append(fields, f)
+			}
+		}
+		p.fields = fields
+	}
+}
+
+// needs 计划是否需要处理, need 返回子计划是否需要处理
+func (p *plan) needs(need func(*plan) bool) bool {
+	switch p.op {
+	case opString, opProto, opInterface:
+		return true
+	case opPtr:
+		return need(p.elem)
+	case opStruct:
+		for _, f := range p.fields {
+			if f.masking || need(f.plan) {
+				return true
+			}
+		}
+		return false
+	case opList:
+		return p.elemMasking || need(p.elem)
+	case opMap:
+		return p.elem == nil || p.keyMasking || need(p.elem)
+	default:
+		return false
+	}
+}
+
+// implementsMasking 静态类型 t 是否实现了 Masking, 接口值的 Masking 按动态类型调用
+func implementsMasking(t reflect.Type) bool {
+	return t.Kind() != reflect.Interface && t.Implements(maskingType)
+}
+
+// walker 按遍历计划处理值, 记录已访问的指针, slice 及 map
+type walker struct {
+	m       *Masker
+	visited map[visit]struct{}
+}
+
+// visit 已访问的值, plan 为空时表示已调用 Masking 方法
+type visit struct {
+	ptr  uintptr
+	typ  reflect.Type
+	len  int
+	plan *plan
+}
+
+// seen v 是否已按计划 p 访问过, 未访问时记录。
+// 同一指针可以从策略不同的多个字段引用, 需按每个计划分别处理; 计划是有限的, 循环引用仍会终止。
+func (w *walker) seen(v reflect.Value, n int, p *plan) bool {
+	return w.mark(visit{ptr: v.Pointer(), typ: v.Type(), len: n, plan: p})
+}
+
+// mark 记录 k, 返回是否已记录过
+func (w *walker) mark(k visit) bool {
+	if w.visited == nil {
+		w.visited = make(map[visit]struct{})
+	}
+	if _, ok := w.visited[k]; ok {
+		return true
+	}
+	w.visited[k] = struct{}{}
+	return false
+}
+
+func (w *walker) walk(v reflect.Value, p *plan) {
+	switch p.op {
+	case opString:
+		if v.CanSet() {
+			v.SetString(p.strategy.fn(v.String()))
+		}
+
+	case opPtr, opProto:
+		if v.IsNil() || w.seen(v, 0, p) {
+			return
+		}
+		w.walkPtr(v, p)
+
+	case opInterface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		if p.strategy != nil && elem.Kind() == reflect.String {
+			if v.CanSet() {
+				v.Set(maskString(elem, p.strategy))
+			}
+			return
+		}
+		w.element(elem, true, w.m.plan(elem.Type(), p.context, p.strategy))
+
+	case opStruct:
+		for _, f := range p.fields {
+			w.element(v.Field(f.index), f.masking, f.plan)
+		}
+
+	case opList:
+		if v.Kind() == reflect.Slice && (v.Len() == 0 || w.seen(v, v.Len(), p)) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			w.element(v.Index(i), p.elemMasking, p.elem)
+		}
+
+	case opMap:
+		if v.Len() == 0 || w.seen(v, 0, p) {
+			return
+		}
+		w.walkMap(v, p)
+	}
+}
+
+// walkPtr 处理非空且未访问过的指针
+func (w *walker) walkPtr(v reflect.Value, p *plan) {
+	switch p.op {
+	case opPtr:
+		w.walk(v.Elem(), p.elem)
+	case opProto:
+		w.m.maskMessage(v.Interface().(proto.Message).ProtoReflect(), p.context, p.strategy.mask())
+	}
+}
+
+// element 调用 v 实现的 Masking 方法后按计划处理 v, 指针的 Masking 方法只调用一次, 每个计划只处理一次
+func (w *walker) element(v reflect.Value, masking bool, p *plan) {
+	if !masking {
+		w.walk(v, p)
+		return
+	}
+	if v.Kind() != reflect.Ptr {
+		callMasking(v)
+		w.walk(v, p)
+		return
+	}
+	if v.IsNil() {
+		return
+	}
+	if !w.seen(v, 0, nil) {
+		callMasking(v)
+	}
+	if w.seen(v, 0, p) {
+		return
+	}
+	w.walkPtr(v, p)
+}
+
+func (w *walker) walkMap(v reflect.Value, p *plan) {
+	iter := v.MapRange()
+	for iter.Next() {
+		key, value := iter.Key(), iter.Value()
+		ep, s := p.elem, p.strategy
+		if ep == nil {
+			path := appendPath(p.context, key.String())
+			s = w.m.match(path, p.strategy)
+			ep = w.m.plan(p.typ.Elem(), w.m.context(path), s)
+		}
+		// map 的值不可寻址, 字符串值通过 SetMapIndex 替换
+		if str := stringValue(value); s != nil && str.IsValid() {
+			v.SetMapIndex(key, maskString(str, s))
+		} else if ep.op != opSkip {
+			w.walk(value, ep)
+		}
+		if p.keyMasking {
+			callMasking(key)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type maskedNode struct {
+	Phone    string `mask:"phone"`
+	Next     *maskedNode
+	Children []*maskedNode
+	Props    map[string]interface{}
+}
+
+type countedNode struct {
+	Next  *countedNode
+	count int
+}
+
+func (n *countedNode) Masking() {
+	n.count++
+}
+
+type plainNode struct {
+	Name     string
+	Next     *plainNode
+	Children []plainNode
+	Created  time.Time
+}
+
+func TestMasker_Cycle(t *testing.T) {
+	n := &maskedNode{Phone: "13812345678", Props: map[string]interface{}{}}
+	n.Next = n
+	n.Children = []*maskedNode{n, {Phone: "13912345678", Next: n}}
+	n.Props["self"] = n
+	n.Props["children"] = n.Children
+	DeepCheck(n)
+	assert.Equal(t, "138****5678", n.Phone)
+	assert.Equal(t, "139****5678", n.Children[1].Phone)
+
+	// 共享的指针只处理一次
+	shared := &countedNode{}
+	c := &countedNode{Next: shared}
+	shared.Next = c
+	DeepCheck(&struct{ A, B *countedNode }{A: c, B: shared})
+	assert.Equal(t, 1, c.count)
+	assert.Equal(t, 1, shared.count)
+}
+
+type sharedUser struct {
+	Phone string
+	Email string `mask:"email"`
+}
+
+func TestMasker_SharedPointer(t *testing.T) {
+	// 同一指针被策略不同的字段引用时, 按每个字段的策略处理
+	u := &sharedUser{Phone: "13812345678", Email: "alice@example.com"}
+	v := &struct {
+		Owner  *sharedUser
+		Secret *sharedUser `mask:"redact"`
+	}{Owner: u, Secret: u}
+	DeepCheck(v)
+	assert.Equal(t, "******", v.Secret.Phone)
+	// 字段自身的标签优先于继承的策略
+	assert.Equal(t, "a****@example.com", v.Secret.Email)
+
+	u = &sharedUser{Phone: "13812345678", Email: "alice@example.com"}
+	list := []*sharedUser{u}
+	w := &struct {
+		Owners  []*sharedUser
+		Secrets []*sharedUser `mask:"redact"`
+		Props   map[string]*sharedUser
+	}{Owners: list, Secrets: list, Props: map[string]*sharedUser{"u": u}}
+	DeepCheck(w)
+	assert.Equal(t, "******", u.Phone)
+
+	// Masking 方法仍只调用一次
+	c := &countedNode{}
+	DeepCheck(&struct {
+		A *countedNode
+		B *countedNode `mask:"redact"`
+	}{A: c, B: c})
+	assert.Equal(t, 1, c.count)
+}
+
+func TestMasker_Plan(t *testing.T) {
+	m, err := NewMasker(map[string]string{"user.phone": "phone"})
+	assert.Nil(t, err)
+
+	// 不包含需要处理的值的类型被跳过, 包括递归类型
+	p := m.plan(reflect.TypeOf(&plainNode{}), nil, nil)
+	assert.Equal(t, opSkip, p.op)
+	p = m.plan(reflect.TypeOf(&maskedNode{}), nil, nil)
+	assert.Equal(t, opPtr, p.op)
+	assert.Equal(t, opStruct, p.elem.op)
+	assert.Len(t, p.elem.fields, 4)
+	assert.Same(t, p, m.plan(reflect.TypeOf(&maskedNode{}), nil, nil))
+
+	// 规则按路径匹配, 同一类型在不同路径下的计划不同
+	type account struct {
+		Phone string
+	}
+	type wrapper struct {
+		User  account
+		Admin account
+	}
+	p = m.plan(reflect.TypeOf(wrapper{}), nil, nil)
+	assert.Len(t, p.fields, 1)
+	assert.Equal(t, 0, p.fields[0].index)
+	w := &wrapper{User: account{Phone: "13812345678"}, Admin: account{Phone: "13812345678"}}
+	m.Mask(w)
+	assert.Equal(t, "138****5678", w.User.Phone)
+	assert.Equal(t, "13812345678", w.Admin.Phone)
+}
+
+func TestMasker_MapKeyPlans(t *testing.T) {
+	m, err := NewMasker(map[string]string{"user.phone": "phone"})
+	assert.Nil(t, err)
+	plans := func() int {
+		var n int
+		m.plans.Range(func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+	mask := func(n int) map[string]map[string]string {
+		data := map[string]map[string]string{"user": {"phone": "13812345678"}}
+		for i := 0; i < n; i++ {
+			data[fmt.Sprint("key", i)] = map[string]string{"phone": "13812345678"}
+		}
+		m.Mask(data)
+		assert.Equal(t, "138****5678", data["user"]["phone"])
+		assert.Equal(t, "13812345678", data["key0"]["phone"])
+		return data
+	}
+	// map 的 key 来自数据, 不在规则中的 key 共用遍历计划, 计划的数量不随 key 的数量增长
+	mask(10)
+	n := plans()
+	mask(1000)
+	assert.Equal(t, n, plans())
+}
+
+func TestMasker_Concurrent(t *testing.T) {
+	m, err := NewMasker(map[string]string{"detail": "redact"})
+	assert.Nil(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u := &maskedUser{Phone: "13812345678", Address: &maskedAddress{Detail: "detail"}}
+			u.Children = []*maskedUser{u}
+			m.Mask(u)
+			assert.Equal(t, "138****5678", u.Phone)
+			assert.Equal(t, redacted, u.Address.Detail)
+		}()
+	}
+	wg.Wait()
+}
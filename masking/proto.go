@@ -0,0 +1,160 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"trpc.group/trpc-go/trpc-filter/masking/maskpb"
+)
+
+// maskMessage 按 protobuf 反射遍历消息, 包括 oneof, repeated 及 map 字段,
+// 字段的脱敏策略优先使用 (trpc.mask) 及 (trpc.mask_strategy) 选项, 其次是配置规则
+func (m *Masker) maskMessage(msg protoreflect.Message, path []string, s Strategy) {
+	if !msg.IsValid() {
+		return
+	}
+	if v, ok := msg.Interface().(Masking); ok {
+		v.Masking()
+	}
+	// Range 过程中不修改消息, 字符串字段在遍历后统一替换
+	type update struct {
+		fd protoreflect.FieldDescriptor
+		v  protoreflect.Value
+	}
+	var updates []update
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fieldPath := appendPath(path, string(fd.Name()))
+		fs := m.protoFieldStrategy(fd, fieldPath, s)
+		switch {
+		case fd.IsList():
+			m.maskList(fd, v.List(), fieldPath, fs)
+		case fd.IsMap():
+			m.maskMap(fd.MapValue(), v.Map(), fieldPath, fs)
+		case fd.Message() != nil:
+			m.maskMessage(v.Message(), fieldPath, fs)
+		case fd.Kind() == protoreflect.StringKind && fs != nil:
+			updates = append(updates, update{fd: fd, v: protoreflect.ValueOfString(fs(v.String()))})
+		}
+		return true
+	})
+	for _, u := range updates {
+		msg.Set(u.fd, u.v)
+	}
+}
+
+func (m *Masker) maskList(fd protoreflect.FieldDescriptor, list protoreflect.List, path []string, s Strategy) {
+	for i := 0; i < list.Len(); i++ {
+		switch {
+		case fd.Message() != nil:
+			m.maskMessage(list.Get(i).Message(), path, s)
+		case fd.Kind() == protoreflect.StringKind && s != nil:
+			list.Set(i, protoreflect.ValueOfString(s(list.Get(i).String())))
+		}
+	}
+}
+
+func (m *Masker) maskMap(fd protoreflect.FieldDescriptor, mp protoreflect.Map, path []string, s Strategy) {
+	type update struct {
+		k protoreflect.MapKey
+		v protoreflect.Value
+	}
+	var updates []update
+	mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		valuePath, vs := path, s
+		if key, ok := k.Interface().(string); ok {
+			valuePath = appendPath(path, key)
+			vs = m.matchFunc(valuePath, s)
+		}
+		switch {
+		case fd.Message() != nil:
+			m.maskMessage(v.Message(), valuePath, vs)
+		case fd.Kind() == protoreflect.StringKind && vs != nil:
+			updates = append(updates, update{k: k, v: protoreflect.ValueOfString(vs(v.String()))})
+		}
+		return true
+	})
+	for _, u := range updates {
+		mp.Set(u.k, u.v)
+	}
+}
+
+// matchFunc 返回 path 匹配的规则的策略函数, 没有匹配时继承上层的策略
+func (m *Masker) matchFunc(path []string, inherited Strategy) Strategy {
+	if s, ok := m.matchRule(path); ok {
+		return s.mask()
+	}
+	return inherited
+}
+
+// protoFieldStrategy 返回字段的脱敏策略, 字段选项优先于配置规则, 都没有时继承上层的策略
+func (m *Masker) protoFieldStrategy(fd protoreflect.FieldDescriptor, path []string, inherited Strategy) Strategy {
+	if m.exempted(path) {
+		return nil
+	}
+	if o := protoFieldOption(fd); o.ok {
+		return o.strategy
+	}
+	return m.matchFunc(path, inherited)
+}
+
+// fieldOption 字段选项的脱敏策略, ok 为 false 时字段没有脱敏选项
+type fieldOption struct {
+	strategy Strategy
+	ok       bool
+}
+
+// fieldOptions 按字段全名缓存字段选项的脱敏策略, 避免每次遍历都解析选项
+var fieldOptions sync.Map // map[protoreflect.FullName]fieldOption
+
+// protoFieldOption 返回字段选项的脱敏策略, 没有时解析并缓存
+func protoFieldOption(fd protoreflect.FieldDescriptor) fieldOption {
+	if o, ok := fieldOptions.Load(fd.FullName()); ok {
+		return o.(fieldOption)
+	}
+	o := parseFieldOption(fd)
+	fieldOptions.Store(fd.FullName(), o)
+	return o
+}
+
+// parseFieldOption 解析字段的 (trpc.mask_strategy) 及 (trpc.mask) 选项, 选项错误时全部替换, 避免泄露敏感信息
+func parseFieldOption(fd protoreflect.FieldDescriptor) fieldOption {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return fieldOption{}
+	}
+	var spec string
+	switch {
+	case proto.HasExtension(opts, maskpb.E_MaskStrategy):
+		spec = proto.GetExtension(opts, maskpb.E_MaskStrategy).(string)
+	case proto.HasExtension(opts, maskpb.E_Mask):
+		t := proto.GetExtension(opts, maskpb.E_Mask).(maskpb.MaskType)
+		if t == maskpb.MaskType_NONE {
+			return fieldOption{ok: true}
+		}
+		spec = strings.ToLower(t.String())
+	default:
+		return fieldOption{}
+	}
+	s, err := ParseStrategy(spec)
+	if err != nil {
+		return fieldOption{strategy: redact, ok: true}
+	}
+	return fieldOption{strategy: s, ok: true}
+}
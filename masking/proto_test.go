@@ -0,0 +1,101 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"trpc.group/trpc-go/trpc-filter/masking/internal/testpb"
+)
+
+func newProtoUser() *testpb.User {
+	return &testpb.User{
+		Name:       "alice",
+		Phone:      "13812345678",
+		Card:       "6222021234",
+		Remark:     "remark",
+		IdCard:     "110101199003071234",
+		Emails:     []string{"alice@example.com"},
+		Contacts:   map[string]string{"mom": "13800000000"},
+		Address:    &testpb.Address{Detail: "road", Phone: "13812345678"},
+		Friends:    []*testpb.User{{Phone: "13912345678"}},
+		Addresses:  map[string]*testpb.Address{"work": {Detail: "office"}},
+		Credential: &testpb.User_Password{Password: "secret"},
+	}
+}
+
+func TestMasker_Proto(t *testing.T) {
+	m, err := NewMasker(map[string]string{"id_card": "id_card", "remark": "redact", "address.phone": "phone"})
+	assert.Nil(t, err)
+	u := newProtoUser()
+	m.Mask(u)
+	want := &testpb.User{
+		Name:       "alice",
+		Phone:      "138****5678",
+		Card:       "******1234",
+		Remark:     "remark",
+		IdCard:     "110***********1234",
+		Emails:     []string{"a****@example.com"},
+		Contacts:   map[string]string{"mom": "138****0000"},
+		Address:    &testpb.Address{Detail: "******", Phone: "138****5678"},
+		Friends:    []*testpb.User{{Phone: "139****5678"}},
+		Addresses:  map[string]*testpb.Address{"work": {Detail: "******"}},
+		Credential: &testpb.User_Password{Password: "******"},
+	}
+	assert.True(t, proto.Equal(want, u), u.String())
+
+	home := &testpb.User{Credential: &testpb.User_Home{Home: &testpb.Address{Detail: "home"}}}
+	m.Mask(home)
+	assert.Equal(t, "******", home.GetHome().GetDetail())
+}
+
+func TestMasker_ProtoInStruct(t *testing.T) {
+	rsp := &struct {
+		User  *testpb.User
+		Users map[string]interface{}
+	}{
+		User:  newProtoUser(),
+		Users: map[string]interface{}{"bob": newProtoUser()},
+	}
+	DeepCheck(rsp)
+	assert.Equal(t, "138****5678", rsp.User.Phone)
+	assert.Equal(t, "******", rsp.User.GetPassword())
+	assert.Equal(t, "138****5678", rsp.Users["bob"].(*testpb.User).Phone)
+	DeepCheck((*testpb.User)(nil))
+}
+
+func TestProtoFieldOption(t *testing.T) {
+	fields := (&testpb.User{}).ProtoReflect().Descriptor().Fields()
+	phone := fields.ByName("phone")
+	assert.Equal(t, "138****5678", protoFieldOption(phone).strategy("13812345678"))
+	o, ok := fieldOptions.Load(phone.FullName())
+	assert.True(t, ok)
+	assert.True(t, o.(fieldOption).ok)
+
+	// NONE 及没有选项的字段也被缓存
+	remark := protoFieldOption(fields.ByName("remark"))
+	assert.True(t, remark.ok)
+	assert.Nil(t, remark.strategy)
+	assert.False(t, protoFieldOption(fields.ByName("name")).ok)
+	_, ok = fieldOptions.Load(fields.ByName("name").FullName())
+	assert.True(t, ok)
+
+	// 策略变化时清空缓存
+	RegisterStrategy("proto_cache", func(s string) string { return s })
+	_, ok = fieldOptions.Load(phone.FullName())
+	assert.False(t, ok)
+}
@@ -0,0 +1,180 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 内置脱敏策略名
+const (
+	StrategyPhone     = "phone"      // 手机号, 保留前 3 位及后 4 位
+	StrategyEmail     = "email"      // 邮箱, 保留用户名首字符及域名
+	StrategyIDCard    = "id_card"    // 身份证号, 保留前 3 位及后 4 位
+	StrategyBankCard  = "bank_card"  // 银行卡号, 保留前 6 位及后 4 位
+	StrategyRedact    = "redact"     // 全部替换为 ******
+	StrategyHash      = "hash"       // 替换为 HMAC-SHA256 十六进制摘要, 需通过 SetHashKey 设置密钥
+	StrategyKeepFirst = "keep_first" // keep_first=N, 保留前 N 个字符
+	StrategyKeepLast  = "keep_last"  // keep_last=N, 保留后 N 个字符
+)
+
+const (
+	maskChar = '*'
+	redacted = "******"
+)
+
+// Strategy 脱敏策略, 返回脱敏后的字符串
+type Strategy func(s string) string
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Strategy{
+		StrategyPhone:    func(s string) string { return keep(s, 3, 4) },
+		StrategyEmail:    maskEmail,
+		StrategyIDCard:   func(s string) string { return keep(s, 3, 4) },
+		StrategyBankCard: func(s string) string { return keep(s, 6, 4) },
+		StrategyRedact:   redact,
+	}
+	hashKey atomic.Value // []byte
+)
+
+// RegisterStrategy 注册自定义脱敏策略, 可以在标签及配置规则中使用, 同名时覆盖。
+// 应在使用脱敏器前注册, 已编译的遍历计划不受影响。
+func RegisterStrategy(name string, s Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = s
+	clearParsedStrategies()
+}
+
+// SetHashKey 设置 hash 策略的 HMAC-SHA256 密钥, 未设置时 hash 策略不可用:
+// 手机号, 身份证号等取值范围小, 不加密钥的摘要可以被穷举还原。
+// 应在使用脱敏器前设置, 已编译的遍历计划不受影响。
+func SetHashKey(key []byte) {
+	hashKey.Store(append([]byte(nil), key...))
+	clearParsedStrategies()
+}
+
+func clearParsedStrategies() {
+	for _, m := range []*sync.Map{&parsedStrategies, &fieldOptions} {
+		m.Range(func(key, _ interface{}) bool {
+			m.Delete(key)
+			return true
+		})
+	}
+}
+
+// maskStrategy 解析后的策略, 相同的策略共享同一个实例, 用作遍历计划的缓存键
+type maskStrategy struct {
+	spec string
+	fn   Strategy
+}
+
+// mask 返回策略函数, s 为空时返回 nil
+func (s *maskStrategy) mask() Strategy {
+	if s == nil {
+		return nil
+	}
+	return s.fn
+}
+
+var (
+	parsedStrategies sync.Map // map[string]*maskStrategy
+	redactStrategy   = &maskStrategy{spec: StrategyRedact, fn: redact}
+)
+
+// parseStrategy 解析并缓存策略
+func parseStrategy(spec string) (*maskStrategy, error) {
+	if s, ok := parsedStrategies.Load(spec); ok {
+		return s.(*maskStrategy), nil
+	}
+	fn, err := ParseStrategy(spec)
+	if err != nil {
+		return nil, err
+	}
+	s, _ := parsedStrategies.LoadOrStore(spec, &maskStrategy{spec: spec, fn: fn})
+	return s.(*maskStrategy), nil
+}
+
+// ParseStrategy 解析策略, 格式为策略名或 策略名=参数, 如 phone, keep_last=4
+func ParseStrategy(spec string) (Strategy, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(spec), "=")
+	switch name {
+	case StrategyKeepFirst, StrategyKeepLast:
+		n, err := strconv.Atoi(arg)
+		if !hasArg || err != nil || n < 0 {
+			return nil, fmt.Errorf("masking: invalid strategy %q, want %s=N", spec, name)
+		}
+		if name == StrategyKeepFirst {
+			return func(s string) string { return keep(s, n, 0) }, nil
+		}
+		return func(s string) string { return keep(s, 0, n) }, nil
+	case StrategyHash:
+		key, _ := hashKey.Load().([]byte)
+		if hasArg || len(key) == 0 {
+			return nil, fmt.Errorf("masking: invalid strategy %q, the key of %s is not set", spec, name)
+		}
+		return func(s string) string { return hash(key, s) }, nil
+	}
+	strategiesMu.RLock()
+	s, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok || hasArg {
+		return nil, fmt.Errorf("masking: unknown strategy %q", spec)
+	}
+	return s, nil
+}
+
+// keep 保留前 first 个及后 last 个字符, 其余替换为 *, 字符数不超过保留数时全部替换
+func keep(s string, first, last int) string {
+	r := []rune(s)
+	if len(r) <= first+last {
+		return strings.Repeat(string(maskChar), len(r))
+	}
+	for i := first; i < len(r)-last; i++ {
+		r[i] = maskChar
+	}
+	return string(r)
+}
+
+func maskEmail(s string) string {
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return keep(s, 1, 0)
+	}
+	return keep(s[:at], 1, 0) + s[at:]
+}
+
+func redact(s string) string {
+	if s == "" {
+		return s
+	}
+	return redacted
+}
+
+func hash(key []byte, s string) string {
+	if s == "" {
+		return s
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,75 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStrategy(t *testing.T) {
+	SetHashKey([]byte("key"))
+	defer SetHashKey(nil)
+	tests := []struct {
+		spec string
+		in   string
+		want string
+	}{
+		{"phone", "13812345678", "138****5678"},
+		{"email", "alice@example.com", "a****@example.com"},
+		{"email", "invalid", "i******"},
+		{"id_card", "110101199003071234", "110***********1234"},
+		{"bank_card", "6222021234567890123", "622202*********0123"},
+		{"redact", "secret", "******"},
+		{"redact", "", ""},
+		{"hash", "abc", "9c196e32dc0175f86f4b1cb89289d6619de6bee699e4c378e68309ed97a1a6ab"},
+		{"keep_last=4", "张三丰的卡号1234", "******1234"},
+		{"keep_first=1", "张三丰", "张**"},
+		{"keep_last=4", "123", "***"},
+	}
+	for _, tt := range tests {
+		s, err := ParseStrategy(tt.spec)
+		assert.Nil(t, err, tt.spec)
+		assert.Equal(t, tt.want, s(tt.in), tt.spec)
+	}
+	for _, spec := range []string{"unknown", "keep_last", "keep_last=x", "keep_first=-1", "phone=3"} {
+		_, err := ParseStrategy(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestParseStrategy_HashKey(t *testing.T) {
+	// hash is rejected without a key, since unsalted digests of phone numbers can be enumerated.
+	SetHashKey(nil)
+	_, err := ParseStrategy("hash")
+	assert.NotNil(t, err)
+	_, err = NewMasker(map[string]string{"phone": "hash"})
+	assert.NotNil(t, err)
+
+	SetHashKey([]byte("key"))
+	defer SetHashKey(nil)
+	s, err := ParseStrategy("hash")
+	assert.Nil(t, err)
+	assert.Equal(t, "9c196e32dc0175f86f4b1cb89289d6619de6bee699e4c378e68309ed97a1a6ab", s("abc"))
+	_, err = ParseStrategy("hash=1")
+	assert.NotNil(t, err)
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStrategy("upper_x", func(s string) string { return "X" })
+	s, err := ParseStrategy("upper_x")
+	assert.Nil(t, err)
+	assert.Equal(t, "X", s("abc"))
+}
@@ -0,0 +1,163 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// ClaimsFunc 从 ctx 中获取调用方的声明, 用于按声明放开脱敏, 如从 jwt 拦截器校验通过的 token 中获取。
+// 配置了 claims 条件时必须在插件初始化前设置。
+var ClaimsFunc func(ctx context.Context) map[string]interface{}
+
+// SetClaimsFunc 设置获取声明的函数
+func SetClaimsFunc(f func(ctx context.Context) map[string]interface{}) {
+	ClaimsFunc = f
+}
+
+// UnmaskPolicy 放开脱敏的策略, 配置的条件需全部满足, 多个条件之一满足即可时配置多个策略。
+// 主调服务名及透传信息由调用方设置, 只有在调用方经过认证(如 mTLS, 服务网格的身份认证)
+// 或服务只允许白名单内的调用方访问时才可信, 否则应配置经过认证的声明。
+type UnmaskPolicy struct {
+	Name     string            `yaml:"name"`     // 策略名, 用于审计日志
+	Methods  []string          `yaml:"methods"`  // RPC 名, 支持 path.Match 通配符, 为空时匹配所有方法
+	Callers  []string          `yaml:"callers"`  // 主调服务名, 满足其中之一即可, 需调用方经过认证
+	Metadata map[string]string `yaml:"metadata"` // 透传信息 key -> value, 需调用方经过认证
+	Claims   map[string]string `yaml:"claims"`   // 声明 name -> value, 声明为数组时包含该值即可, 通过 ClaimsFunc 获取
+	Fields   []string          `yaml:"fields"`   // 不脱敏的字段名或路径, 为空时完全不脱敏
+}
+
+// validate 校验策略, 没有调用方条件的策略会对所有调用方放开脱敏
+func (p *UnmaskPolicy) validate() error {
+	if len(p.Callers) == 0 && len(p.Metadata) == 0 && len(p.Claims) == 0 {
+		return errors.New("masking: unmask policy requires callers, metadata or claims")
+	}
+	for _, method := range p.Methods {
+		if _, err := path.Match(method, ""); err != nil {
+			return fmt.Errorf("masking: invalid method %q: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// match 请求是否满足策略
+func (p *UnmaskPolicy) match(ctx context.Context) bool {
+	if p.validate() != nil {
+		return false
+	}
+	msg := trpc.Message(ctx)
+	if len(p.Methods) > 0 && !matchAny(p.Methods, msg.ServerRPCName()) {
+		return false
+	}
+	if len(p.Callers) > 0 && !containsString(p.Callers, msg.CallerServiceName()) {
+		return false
+	}
+	md := msg.ServerMetaData()
+	for k, v := range p.Metadata {
+		if string(md[k]) != v {
+			return false
+		}
+	}
+	if len(p.Claims) == 0 {
+		return true
+	}
+	if ClaimsFunc == nil {
+		return false
+	}
+	claims := ClaimsFunc(ctx)
+	for name, want := range p.Claims {
+		if !claimMatch(claims[name], want) {
+			return false
+		}
+	}
+	return true
+}
+
+// unmaskPolicies 放开脱敏的策略, 按顺序使用第一个满足的策略
+type unmaskPolicies []UnmaskPolicy
+
+// validate 校验所有策略, 配置了 claims 条件时必须已设置 ClaimsFunc, 否则策略永远不会满足
+func (ps unmaskPolicies) validate() error {
+	for i := range ps {
+		if err := ps[i].validate(); err != nil {
+			return fmt.Errorf("%w, policy: %d %s", err, i, ps[i].Name)
+		}
+		if len(ps[i].Claims) > 0 && ClaimsFunc == nil {
+			return fmt.Errorf("masking: unmask policy requires claims but ClaimsFunc is not set, policy: %d %s",
+				i, ps[i].Name)
+		}
+	}
+	return nil
+}
+
+// masker 返回请求使用的脱敏器, 满足放开脱敏的策略时记录审计日志, 完全不脱敏时返回 nil
+func (ps unmaskPolicies) masker(ctx context.Context, m *Masker) *Masker {
+	for i := range ps {
+		p := &ps[i]
+		if !p.match(ctx) {
+			continue
+		}
+		msg := trpc.Message(ctx)
+		log.InfoContextf(ctx, "[masking-audit] unmasked response, policy: %s, caller: %s, method: %s, fields: %v",
+			p.Name, msg.CallerServiceName(), msg.ServerRPCName(), p.Fields)
+		if len(p.Fields) == 0 {
+			return nil
+		}
+		return m.exempt(p.Fields)
+	}
+	return m
+}
+
+// claimMatch 声明值等于 want, 或声明值为数组时包含 want
+func claimMatch(v interface{}, want string) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, e := range v {
+			if fmt.Sprint(e) == want {
+				return true
+			}
+		}
+		return false
+	case []string:
+		return containsString(v, want)
+	default:
+		return fmt.Sprint(v) == want
+	}
+}
+
+func containsString(set []string, s string) bool {
+	for _, v := range set {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
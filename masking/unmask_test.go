@@ -0,0 +1,115 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package masking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+type unmaskUser struct {
+	Phone  string `mask:"phone"`
+	IDCard string `mask:"id_card"`
+}
+
+func newUnmaskCtx(caller, method string, md codec.MetaData) context.Context {
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithCallerServiceName(caller)
+	msg.WithServerRPCName(method)
+	msg.WithServerMetaData(md)
+	return ctx
+}
+
+func TestServerFilter_Unmask(t *testing.T) {
+	defer SetClaimsFunc(nil)
+	SetClaimsFunc(func(ctx context.Context) map[string]interface{} {
+		if v, ok := ctx.Value(testClaimsKey{}).(map[string]interface{}); ok {
+			return v
+		}
+		return nil
+	})
+	f := ServerFilter(WithMasker(&Masker{}), WithUnmaskPolicies(
+		UnmaskPolicy{Name: "cs", Methods: []string{"/trpc.app.user.User/*"}, Callers: []string{"trpc.app.cs.Tool"},
+			Claims: map[string]string{"roles": "cs"}},
+		UnmaskPolicy{Name: "debug", Metadata: map[string]string{"x-unmask": "1"}, Claims: map[string]string{"roles": "dev"},
+			Fields: []string{"phone"}},
+		UnmaskPolicy{Name: "admin", Claims: map[string]string{"roles": "admin"}, Fields: []string{"id_card"}},
+		UnmaskPolicy{Name: "ops", Callers: []string{"trpc.app.ops.Tool"}, Fields: []string{"phone"}},
+		UnmaskPolicy{Name: "trace", Metadata: map[string]string{"x-trace": "1"}, Fields: []string{"id_card"}},
+		UnmaskPolicy{Name: "invalid", Methods: []string{"/trpc.app.user.User/Get"}},
+	))
+	call := func(ctx context.Context) *unmaskUser {
+		rsp, err := f(ctx, &unmaskUser{Phone: "13812345678", IDCard: "110101199003071234"}, handler)
+		assert.Nil(t, err)
+		return rsp.(*unmaskUser)
+	}
+	withRoles := func(ctx context.Context, roles ...interface{}) context.Context {
+		return context.WithValue(ctx, testClaimsKey{}, map[string]interface{}{"roles": roles})
+	}
+	masked := &unmaskUser{Phone: "138****5678", IDCard: "110***********1234"}
+
+	cs := withRoles(newUnmaskCtx("trpc.app.cs.Tool", "/trpc.app.user.User/Get", nil), "cs")
+	assert.Equal(t, &unmaskUser{Phone: "13812345678", IDCard: "110101199003071234"}, call(cs))
+	assert.Equal(t, masked, call(withRoles(newUnmaskCtx("trpc.app.other.Svr", "/trpc.app.user.User/Get", nil), "cs")))
+	assert.Equal(t, masked, call(withRoles(newUnmaskCtx("trpc.app.cs.Tool", "/trpc.app.order.Order/Get", nil), "cs")))
+	assert.Equal(t, masked, call(newUnmaskCtx("trpc.app.cs.Tool", "/trpc.app.user.User/Get", nil)))
+	// Policies with only callers or metadata trust the caller.
+	assert.Equal(t, &unmaskUser{Phone: "13812345678", IDCard: "110***********1234"},
+		call(newUnmaskCtx("trpc.app.ops.Tool", "/trpc.app.user.User/Get", nil)))
+	assert.Equal(t, &unmaskUser{Phone: "138****5678", IDCard: "110101199003071234"},
+		call(newUnmaskCtx("", "/trpc.app.user.User/Get", codec.MetaData{"x-trace": []byte("1")})))
+	assert.Equal(t, masked, call(newUnmaskCtx("", "/trpc.app.order.Order/Get", codec.MetaData{"x-unmask": []byte("1")})))
+	assert.Equal(t, &unmaskUser{Phone: "13812345678", IDCard: "110***********1234"},
+		call(withRoles(newUnmaskCtx("", "/trpc.app.order.Order/Get", codec.MetaData{"x-unmask": []byte("1")}), "dev")))
+	assert.Equal(t, masked,
+		call(withRoles(newUnmaskCtx("", "/trpc.app.order.Order/Get", codec.MetaData{"x-unmask": []byte("0")}), "dev")))
+
+	assert.Equal(t, &unmaskUser{Phone: "138****5678", IDCard: "110101199003071234"},
+		call(withRoles(newUnmaskCtx("", "/a", nil), "ops", "admin")))
+	ctx := context.WithValue(newUnmaskCtx("", "/a", nil), testClaimsKey{}, map[string]interface{}{"roles": "ops"})
+	assert.Equal(t, masked, call(ctx))
+}
+
+type testClaimsKey struct{}
+
+func TestUnmaskPolicies_Validate(t *testing.T) {
+	assert.Nil(t, unmaskPolicies{{Callers: []string{"a"}}}.validate())
+	assert.Nil(t, unmaskPolicies{{Metadata: map[string]string{"a": "b"}}}.validate())
+	assert.NotNil(t, unmaskPolicies{{Methods: []string{"/a"}}}.validate())
+	assert.NotNil(t, unmaskPolicies{{Methods: []string{"/a/["}, Callers: []string{"a"}}}.validate())
+	assert.False(t, (&UnmaskPolicy{}).match(trpc.BackgroundContext()))
+
+	// Claims can not be matched without ClaimsFunc.
+	claims := unmaskPolicies{{Claims: map[string]string{"a": "b"}}}
+	assert.NotNil(t, claims.validate())
+	assert.False(t, claims[0].match(trpc.BackgroundContext()))
+	defer SetClaimsFunc(nil)
+	SetClaimsFunc(func(ctx context.Context) map[string]interface{} { return nil })
+	assert.Nil(t, claims.validate())
+}
+
+func TestMatchAny(t *testing.T) {
+	assert.True(t, matchAny([]string{"/trpc.app.user.User/*"}, "/trpc.app.user.User/Get"))
+	assert.True(t, matchAny([]string{"/a", "/trpc.*.User/Get"}, "/trpc.app.user.User/Get"))
+	assert.True(t, matchAny([]string{"/a"}, "/a"))
+	assert.False(t, matchAny([]string{"/a"}, "/ab"))
+	assert.False(t, matchAny([]string{"*Get"}, "/trpc.app.user.User/Get"))
+	assert.False(t, matchAny([]string{"/trpc.app.user.User/*"}, "/trpc.app.order.Order/Get"))
+	assert.False(t, matchAny(nil, "/a"))
+}
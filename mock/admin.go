@@ -0,0 +1,67 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"trpc.group/trpc-go/trpc-go/admin"
+)
+
+// AdminPattern is the admin path which lists, adds and removes mocks at runtime:
+//   - GET lists the mocks by source.
+//   - POST adds the mock item of the json body, which requires a name and replaces the item of the same name.
+//   - DELETE removes the mock item added at runtime by the name parameter.
+const AdminPattern = "/cmds/mock"
+
+func registerAdmin(store *Store) {
+	admin.HandleFunc(AdminPattern, adminHandler(store))
+}
+
+func adminHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			item := &Item{}
+			if err := json.NewDecoder(r.Body).Decode(item); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				admin.ErrorOutput(w, "mock: invalid item: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := store.Add(item); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				admin.ErrorOutput(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			if !store.Remove(r.URL.Query().Get("name")) {
+				w.WriteHeader(http.StatusNotFound)
+				admin.ErrorOutput(w, "mock: item not found", http.StatusNotFound)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := json.Marshal(store.List())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}
+}
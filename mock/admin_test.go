@@ -0,0 +1,63 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler(t *testing.T) {
+	s := NewStore()
+	s.setConfig([]*Item{{Method: "/a", Percent: 100}})
+	h := adminHandler(s)
+	do := func(method, target, body string) (int, map[string][]*Item) {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var list map[string][]*Item
+		if w.Code == http.StatusOK {
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+		}
+		return w.Code, list
+	}
+
+	code, list := do(http.MethodGet, AdminPattern, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list["config"], 1)
+
+	code, list = do(http.MethodPost, AdminPattern,
+		`{"name":"fault","method":"/a","percent":100,"retcode":111,"match":{"metadata":{"env":"qa"}}}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list["admin"], 1)
+	assert.Equal(t, "qa", list["admin"][0].Match.Metadata["env"])
+	assert.Equal(t, 111, s.Items()[0].Retcode)
+
+	code, _ = do(http.MethodPost, AdminPattern, `{"method":"/a"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do(http.MethodPost, AdminPattern, `{`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, list = do(http.MethodDelete, AdminPattern+"?name=fault", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list["admin"], 0)
+	code, _ = do(http.MethodDelete, AdminPattern+"?name=fault", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(http.MethodPut, AdminPattern, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -0,0 +1,230 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	yaml "gopkg.in/yaml.v3"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// reloadDelay merges the file events in a short time into one reload.
+var reloadDelay = 100 * time.Millisecond
+
+// loadDir loads the mock items of the files in dir, the subdirectories are loaded recursively:
+//   - .yaml, .yml and .json files contain a mock item or a list of mock items.
+//   - .pb and .bin files are binary pb bodies, which always take effect, they must be at <service>/<method>.<ext>.
+//
+// For files at <service>/<method>.<ext>, the method of items defaults to /<service>/<method>.
+func loadDir(dir string) ([]*Item, error) {
+	var items []*Item
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		loaded, err := loadFile(path, rel)
+		if err != nil {
+			return fmt.Errorf("mock: load %s err: %w", path, err)
+		}
+		items = append(items, loaded...)
+		return nil
+	})
+	return items, err
+}
+
+func loadFile(path, rel string) ([]*Item, error) {
+	ext := filepath.Ext(rel)
+	var method string
+	if name := filepath.ToSlash(strings.TrimSuffix(rel, ext)); strings.Contains(name, "/") {
+		method = "/" + name
+	}
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml", ".json":
+	case ".pb", ".bin":
+		// A body without method would be returned for every RPC.
+		if method == "" {
+			return nil, fmt.Errorf("method of binary body is unknown, put it at <service>/<method>%s", ext)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		item := &Item{
+			Method:        method,
+			Percent:       100,
+			Serialization: codec.SerializationTypePB,
+			Body:          base64.StdEncoding.EncodeToString(data),
+		}
+		return []*Item{item}, item.setup()
+	default:
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []*Item
+	if strings.EqualFold(ext, ".json") {
+		items, err = decodeJSONItems(data)
+	} else {
+		items, err = decodeYAMLItems(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item == nil {
+			return nil, fmt.Errorf("empty mock item")
+		}
+		if item.Method == "" {
+			item.Method = method
+		}
+		if err := item.setup(); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// decodeJSONItems decodes a mock item or a list of mock items in json, the same as the admin api.
+func decodeJSONItems(data []byte) ([]*Item, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if data[0] == '[' {
+		var items []*Item
+		err := json.Unmarshal(data, &items)
+		return items, err
+	}
+	item := &Item{}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, err
+	}
+	return []*Item{item}, nil
+}
+
+// decodeYAMLItems decodes a mock item or a list of mock items in yaml.
+func decodeYAMLItems(data []byte) ([]*Item, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+	if node.Content[0].Kind == yaml.SequenceNode {
+		var items []*Item
+		err := node.Decode(&items)
+		return items, err
+	}
+	item := &Item{}
+	if err := node.Decode(item); err != nil {
+		return nil, err
+	}
+	return []*Item{item}, nil
+}
+
+// dirWatcher reloads the mock items of the directory into the store on file changes.
+type dirWatcher struct {
+	dir     string
+	store   *Store
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	once    sync.Once
+}
+
+func watchDir(dir string, store *Store) (*dirWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &dirWatcher{dir: dir, store: store, watcher: watcher, done: make(chan struct{})}
+	if err := w.addDirs(); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// addDirs watches the directory and its subdirectories, since fsnotify does not watch recursively.
+func (w *dirWatcher) addDirs() error {
+	return filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+func (w *dirWatcher) run() {
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = w.addDirs()
+				}
+			}
+			reload = time.After(reloadDelay)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("mock: watch %s err: %v", w.dir, err)
+		case <-reload:
+			reload = nil
+			items, err := loadDir(w.dir)
+			if err != nil {
+				log.Errorf("%v, keep the previous mocks", err)
+				continue
+			}
+			w.store.setFiles(items)
+			log.Infof("mock: %d mocks reloaded from %s", len(items), w.dir)
+		}
+	}
+}
+
+func (w *dirWatcher) close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.watcher.Close()
+	})
+	return err
+}
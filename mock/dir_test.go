@@ -0,0 +1,131 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/plugin"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "trpc.app.server.Greeter", "SayHello.json"),
+		`{"percent": 100, "serialization": 2, "body": "{\"msg\":\"hello\"}"}`)
+	writeFile(t, filepath.Join(dir, "trpc.app.server.Greeter", "SayHi.yaml"), `
+- percent: 100
+  retcode: 1001
+- method: /trpc.app.server.Greeter/Other
+  percent: 100
+  retcode: 1002
+`)
+	writeFile(t, filepath.Join(dir, "trpc.app.server.Greeter", "Bin.pb"), "\x0a\x03abc")
+	writeFile(t, filepath.Join(dir, "all.yml"), `percent: 10`)
+	writeFile(t, filepath.Join(dir, "README.md"), `mocks`)
+	writeFile(t, filepath.Join(dir, "empty.yaml"), ``)
+	writeFile(t, filepath.Join(dir, "list.json"), `
+[{"method": "/trpc.app.server.Greeter/Json", "percent": 100, "fail_after": 2}]`)
+
+	items, err := loadDir(dir)
+	assert.Nil(t, err)
+	methods := make(map[string]*Item)
+	for _, item := range items {
+		methods[item.Method] = item
+	}
+	assert.Len(t, items, 6)
+	assert.Equal(t, []byte(`{"msg":"hello"}`), methods["/trpc.app.server.Greeter/SayHello"].data)
+	assert.Equal(t, 1001, methods["/trpc.app.server.Greeter/SayHi"].Retcode)
+	assert.Equal(t, 1002, methods["/trpc.app.server.Greeter/Other"].Retcode)
+	assert.Equal(t, []byte("\x0a\x03abc"), methods["/trpc.app.server.Greeter/Bin"].data)
+	assert.Equal(t, codec.SerializationTypePB, methods["/trpc.app.server.Greeter/Bin"].Serialization)
+	assert.Equal(t, 10, methods[""].Percent)
+	// json files are decoded as json, the same as the admin api.
+	assert.Equal(t, 2, methods["/trpc.app.server.Greeter/Json"].FailAfter)
+	writeFile(t, filepath.Join(dir, "bad.json"), `{percent: 100}`)
+	_, err = loadDir(dir)
+	assert.NotNil(t, err)
+	assert.Nil(t, os.Remove(filepath.Join(dir, "bad.json")))
+
+	// A binary body without method is not loaded as a mock of every RPC.
+	writeFile(t, filepath.Join(dir, "top.pb"), "\x0a\x03abc")
+	_, err = loadDir(dir)
+	assert.NotNil(t, err)
+	assert.Nil(t, os.Remove(filepath.Join(dir, "top.pb")))
+
+	writeFile(t, filepath.Join(dir, "bad.yaml"), `- body: not base64`)
+	_, err = loadDir(dir)
+	assert.NotNil(t, err)
+	_, err = loadDir(filepath.Join(dir, "not_exist"))
+	assert.NotNil(t, err)
+}
+
+func TestPlugin_MockDir(t *testing.T) {
+	reloadDelay = 10 * time.Millisecond
+	dir := t.TempDir()
+	file := filepath.Join(dir, "trpc.app.server.Greeter", "SayHello.yaml")
+	writeFile(t, file, `{percent: 100, retcode: 1001}`)
+
+	p := &Plugin{}
+	defer p.Close()
+	assert.Nil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, `
+mock_dir: `+dir+`
+watch: true
+mocks:
+  - percent: 100
+    retcode: 1
+`)}))
+	retcodes := func() []int {
+		var codes []int
+		for _, item := range DefaultStore.Items() {
+			codes = append(codes, item.Retcode)
+		}
+		return codes
+	}
+	assert.Equal(t, []int{1001, 1}, retcodes())
+
+	writeFile(t, file, `{percent: 100, retcode: 1002}`)
+	assert.Eventually(t, func() bool {
+		codes := retcodes()
+		return len(codes) == 2 && codes[0] == 1002
+	}, time.Second, 10*time.Millisecond)
+
+	// Files of new directories are watched.
+	writeFile(t, filepath.Join(dir, "trpc.app.server.Other", "Hello.bin"), "\x0a\x03abc")
+	assert.Eventually(t, func() bool { return len(retcodes()) == 3 }, time.Second, 10*time.Millisecond)
+	bin := DefaultStore.Items()[1]
+	assert.Equal(t, "/trpc.app.server.Other/Hello", bin.Method)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("\x0a\x03abc")), bin.Body)
+
+	// Invalid files keep the previous mocks.
+	writeFile(t, file, `{percent: 100, mode: unknown}`)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, retcodes(), 3)
+
+	assert.Nil(t, p.Close())
+	assert.Nil(t, p.watcher)
+
+	assert.NotNil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, `mock_dir: `+dir)}))
+	assert.NotNil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, `mock_dir: `+filepath.Join(dir, "not_exist"))}))
+}
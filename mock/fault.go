@@ -0,0 +1,225 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+// Connection-level errors injected by Error.
+const (
+	ErrorNet         = "net_err"      // RetClientNetErr
+	ErrorConnectFail = "connect_fail" // RetClientConnectFail
+)
+
+// Latency distributions.
+const (
+	DistributionUniform = "uniform" // uniform in [min, max)
+	DistributionNormal  = "normal"  // normal with mean and stddev
+	DistributionPareto  = "pareto"  // long tail pareto with p50 and p99 targets
+)
+
+// pushbackKey is the metadata key of the pushback delay, which is recognized by slime retry.
+const pushbackKey = "trpc-pushback-delay"
+
+// corruptedData cannot be decoded by pb or json.
+var corruptedData = []byte{0xff, 0xff, 0xff}
+
+// Latency latency drawn from a distribution, all the values are in milliseconds.
+type Latency struct {
+	Distribution string
+	Min          int // minimum of uniform
+	Max          int // maximum of uniform, and the cap of the other distributions if set
+	Mean         int // mean of normal
+	Stddev       int // standard deviation of normal
+	P50          int // median of pareto
+	P99          int // 99th percentile of pareto
+}
+
+// setup validates the distribution.
+func (l *Latency) setup() error {
+	if l == nil {
+		return nil
+	}
+	switch l.Distribution {
+	case DistributionUniform:
+		if l.Min < 0 || l.Max <= l.Min {
+			return fmt.Errorf("mock latency: uniform requires 0 <= min < max")
+		}
+	case DistributionNormal:
+		if l.Mean < 0 || l.Stddev < 0 {
+			return fmt.Errorf("mock latency: normal requires non-negative mean and stddev")
+		}
+	case DistributionPareto:
+		if l.P50 <= 0 || l.P99 <= l.P50 {
+			return fmt.Errorf("mock latency: pareto requires 0 < p50 < p99")
+		}
+	default:
+		return fmt.Errorf("mock latency: unknown distribution %s", l.Distribution)
+	}
+	if l.Max < 0 {
+		return fmt.Errorf("mock latency: negative max")
+	}
+	return nil
+}
+
+// sample draws a latency from the distribution.
+func (l *Latency) sample() time.Duration {
+	var ms float64
+	switch l.Distribution {
+	case DistributionUniform:
+		ms = float64(l.Min) + rand.Float64()*float64(l.Max-l.Min)
+	case DistributionNormal:
+		ms = float64(l.Mean) + rand.NormFloat64()*float64(l.Stddev)
+	case DistributionPareto:
+		// The quantile of pareto is scale / (1-p)^(1/shape), so p99 / p50 = 50^(1/shape).
+		shape := math.Log(50) / math.Log(float64(l.P99)/float64(l.P50))
+		scale := float64(l.P50) / math.Pow(2, 1/shape)
+		ms = scale / math.Pow(1-rand.Float64(), 1/shape)
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	if l.Max > 0 && ms > float64(l.Max) {
+		ms = float64(l.Max)
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// setupFault validates the fault injection fields of the response.
+func (r *Response) setupFault() error {
+	switch r.Error {
+	case "", ErrorNet, ErrorConnectFail:
+	default:
+		return fmt.Errorf("mock: unknown error %s", r.Error)
+	}
+	if r.Pushback != "" {
+		if _, err := time.ParseDuration(r.Pushback); err != nil {
+			return fmt.Errorf("mock: invalid pushback %s: %w", r.Pushback, err)
+		}
+	}
+	return r.Latency.setup()
+}
+
+// scheduled reports whether the mock item takes effect on the current call by fail_after and fail_first_n:
+// the first FailAfter calls are not affected, and then FailFirstN calls are affected if FailFirstN is set.
+func (m *Item) scheduled() bool {
+	if m.FailAfter <= 0 && m.FailFirstN <= 0 {
+		return true
+	}
+	m.mu.Lock()
+	m.matched++
+	n := m.matched
+	m.mu.Unlock()
+	if n <= m.FailAfter {
+		return false
+	}
+	return m.FailFirstN <= 0 || n <= m.FailAfter+m.FailFirstN
+}
+
+// fault injects the timeout, latency and errors of the response.
+func (r *Response) fault(ctx context.Context, msg codec.Msg, server bool) error {
+	if r.Timeout {
+		<-ctx.Done()
+		return r.withPushback(msg, server, ctxError(ctx, server, "mock filter: timeout"))
+	}
+	var d time.Duration
+	if r.Latency != nil {
+		d = r.Latency.sample()
+	} else if r.Delay > 0 {
+		d = r.delay
+	}
+	if d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return r.withPushback(msg, server, ctxError(ctx, server, "mock filter: timeout during delay mock"))
+		case <-t.C:
+		}
+	}
+	switch r.Error {
+	case ErrorNet:
+		return r.withPushback(msg, server, errs.NewFrameError(errs.RetClientNetErr, "mock filter: network error"))
+	case ErrorConnectFail:
+		return r.withPushback(msg, server, errs.NewFrameError(errs.RetClientConnectFail, "mock filter: connect fail"))
+	}
+	if r.Retcode > 0 {
+		return r.withPushback(msg, server, errs.New(r.Retcode, r.Retmsg))
+	}
+	return nil
+}
+
+// ctxError returns the timeout error, or the canceled error of the client, e.g. canceled by hedging.
+func ctxError(ctx context.Context, server bool, msg string) error {
+	if server {
+		return errs.NewFrameError(errs.RetServerTimeout, msg)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return errs.NewFrameError(errs.RetClientCanceled, msg)
+	}
+	return errs.NewFrameError(errs.RetClientTimeout, msg)
+}
+
+// withPushback sets the pushback delay into the metadata of the response, and returns err.
+func (r *Response) withPushback(msg codec.Msg, server bool, err error) error {
+	if r.Pushback == "" {
+		return err
+	}
+	if server {
+		md := msg.ServerMetaData().Clone()
+		if md == nil {
+			md = codec.MetaData{}
+		}
+		md[pushbackKey] = []byte(r.Pushback)
+		msg.WithServerMetaData(md)
+		return err
+	}
+	md := msg.ClientMetaData().Clone()
+	if md == nil {
+		md = codec.MetaData{}
+	}
+	md[pushbackKey] = []byte(r.Pushback)
+	msg.WithClientMetaData(md)
+	return err
+}
+
+// corruptResponse decodes corrupted data into rsp, and returns the decoding error.
+func corruptResponse(serialization int, rsp interface{}) error {
+	err := codec.Unmarshal(serialization, corruptedData, rsp)
+	if err == nil {
+		err = errors.New("corrupted response")
+	}
+	return errs.NewFrameError(errs.RetClientDecodeFail, "mock filter Unmarshal: "+err.Error())
+}
+
+// corruptedResponse returns a response which cannot be decoded by the client.
+func corruptedResponse(serialization int) (interface{}, error) {
+	if serialization != codec.SerializationTypePB {
+		return nil, errs.NewFrameError(errs.RetServerEncodeFail, "mock filter: corrupted response")
+	}
+	rsp := &emptypb.Empty{}
+	rsp.ProtoReflect().SetUnknown(protoreflect.RawFields(corruptedData))
+	return rsp, nil
+}
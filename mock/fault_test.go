@@ -0,0 +1,161 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/plugin"
+)
+
+func samples(l *Latency, n int) []time.Duration {
+	s := make([]time.Duration, n)
+	for i := range s {
+		s[i] = l.sample()
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s
+}
+
+func TestLatency(t *testing.T) {
+	const n = 20000
+	uniform := &Latency{Distribution: DistributionUniform, Min: 10, Max: 20}
+	assert.Nil(t, uniform.setup())
+	s := samples(uniform, n)
+	assert.GreaterOrEqual(t, s[0], 10*time.Millisecond)
+	assert.Less(t, s[n-1], 20*time.Millisecond)
+
+	normal := &Latency{Distribution: DistributionNormal, Mean: 100, Stddev: 10, Max: 120}
+	assert.Nil(t, normal.setup())
+	s = samples(normal, n)
+	assert.InDelta(t, float64(100*time.Millisecond), float64(s[n/2]), float64(2*time.Millisecond))
+	assert.Equal(t, 120*time.Millisecond, s[n-1])
+
+	pareto := &Latency{Distribution: DistributionPareto, P50: 10, P99: 200}
+	assert.Nil(t, pareto.setup())
+	s = samples(pareto, n)
+	assert.InEpsilon(t, float64(10*time.Millisecond), float64(s[n/2]), 0.1)
+	assert.InEpsilon(t, float64(200*time.Millisecond), float64(s[n*99/100]), 0.25)
+
+	for _, l := range []*Latency{
+		{Distribution: "unknown"},
+		{Distribution: DistributionUniform, Min: 10, Max: 10},
+		{Distribution: DistributionNormal, Mean: -1},
+		{Distribution: DistributionPareto, P50: 10, P99: 10},
+		{Distribution: DistributionNormal, Mean: 10, Max: -1},
+	} {
+		assert.NotNil(t, l.setup(), l)
+	}
+}
+
+func TestClientFilter_Fault(t *testing.T) {
+	call := func(item *Item) (error, codec.Msg) {
+		assert.Nil(t, item.setup())
+		ctx, cancel := context.WithTimeout(trpc.BackgroundContext(), 50*time.Millisecond)
+		defer cancel()
+		shared := codec.MetaData{"k": []byte("v")}
+		trpc.Message(ctx).WithClientMetaData(shared)
+		err := ClientFilter(WithMock(item))(ctx, nil, &struct{}{}, noopHandler)
+		assert.NotContains(t, shared, pushbackKey)
+		return err, trpc.Message(ctx)
+	}
+
+	err, msg := call(&Item{Percent: 100, Error: ErrorNet, Pushback: "20ms"})
+	assert.Equal(t, errs.RetClientNetErr, errs.Code(err))
+	assert.Equal(t, errs.ErrorTypeFramework, err.(*errs.Error).Type)
+	assert.Equal(t, "20ms", string(msg.ClientMetaData()[pushbackKey]))
+	assert.Equal(t, "v", string(msg.ClientMetaData()["k"]))
+
+	err, msg = call(&Item{Percent: 100, Error: ErrorConnectFail})
+	assert.Equal(t, errs.RetClientConnectFail, errs.Code(err))
+	assert.NotContains(t, msg.ClientMetaData(), pushbackKey)
+
+	err, msg = call(&Item{Percent: 100, Retcode: 1001, Pushback: "-1ms"})
+	assert.EqualValues(t, 1001, errs.Code(err))
+	assert.Equal(t, "-1ms", string(msg.ClientMetaData()[pushbackKey]))
+
+	err, _ = call(&Item{Percent: 100, Corrupt: true, Serialization: codec.SerializationTypeJSON})
+	assert.Equal(t, errs.RetClientDecodeFail, errs.Code(err))
+
+	err, _ = call(&Item{Percent: 100, Latency: &Latency{Distribution: DistributionUniform, Min: 100, Max: 200}})
+	assert.Equal(t, errs.RetClientTimeout, errs.Code(err))
+
+	// Canceled by hedging.
+	ctx, cancel := context.WithCancel(trpc.BackgroundContext())
+	cancel()
+	err = ClientFilter(WithMock(&Item{Percent: 100, Timeout: true}))(ctx, nil, nil, noopHandler)
+	assert.Equal(t, errs.RetClientCanceled, errs.Code(err))
+
+	var p Plugin
+	for _, conf := range []string{
+		`[{error: unknown}]`,
+		`[{pushback: 1}]`,
+		`[{latency: {distribution: pareto, p50: 10}}]`,
+		`[{responses: [{latency: {distribution: uniform}}]}]`,
+	} {
+		assert.NotNil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, conf)}), conf)
+	}
+}
+
+func TestServerFilter_Fault(t *testing.T) {
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	f := ServerFilter(WithMock(&Item{Percent: 100, Error: ErrorNet, Pushback: "10ms"}))
+	_, err := f(ctx, nil, noopServerHandler)
+	assert.Equal(t, errs.RetClientNetErr, errs.Code(err))
+	assert.Equal(t, "10ms", string(msg.ServerMetaData()[pushbackKey]))
+
+	// The corrupted pb response cannot be decoded by the client.
+	rsp, err := ServerFilter(WithMock(&Item{Percent: 100, Corrupt: true}))(ctx, nil, noopServerHandler)
+	assert.Nil(t, err)
+	data, err := codec.Marshal(codec.SerializationTypePB, rsp)
+	assert.Nil(t, err)
+	assert.NotNil(t, proto.Unmarshal(data, &wrapperspb.StringValue{}))
+	// The response is corrupted in the serialization of the request.
+	msg.WithSerializationType(codec.SerializationTypeJSON)
+	_, err = ServerFilter(WithMock(&Item{Percent: 100, Corrupt: true}))(ctx, nil, noopServerHandler)
+	assert.Equal(t, errs.RetServerEncodeFail, errs.Code(err))
+}
+
+func TestItem_Schedule(t *testing.T) {
+	codes := func(item *Item, n int) []int {
+		assert.Nil(t, item.setup())
+		f := ClientFilter(WithMock(item))
+		var got []int
+		for i := 0; i < n; i++ {
+			got = append(got, int(errs.Code(f(trpc.BackgroundContext(), nil, nil, noopHandler))))
+		}
+		return got
+	}
+	// Succeed after the first 2 failures, e.g. to test retries.
+	assert.Equal(t, []int{1, 1, 0, 0}, codes(&Item{Percent: 100, Retcode: 1, FailFirstN: 2}, 4))
+	// Fail after the first 2 calls.
+	assert.Equal(t, []int{0, 0, 1, 1}, codes(&Item{Percent: 100, Retcode: 1, FailAfter: 2}, 4))
+	// Fail in a window.
+	assert.Equal(t, []int{0, 1, 1, 0}, codes(&Item{Percent: 100, Retcode: 1, FailAfter: 1, FailFirstN: 2}, 4))
+
+	var conf Config
+	assert.Nil(t, yamlNode(t, `[{fail_after: 1, fail_first_n: 2}]`).Decode(&conf))
+	assert.Equal(t, 1, conf[0].FailAfter)
+	assert.Equal(t, 2, conf[0].FailFirstN)
+}
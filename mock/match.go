@@ -0,0 +1,191 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+// Match request-matching conditions of a mock item, all the configured conditions must be satisfied.
+type Match struct {
+	Caller   string            // caller service name
+	Metadata map[string]string // metadata key -> value, an empty value only requires the key to exist
+	Body     []*BodyMatch      // conditions on fields of the request serialized as json
+}
+
+// BodyMatch condition on a field of the request serialized as json.
+// When neither Equals nor Regex is set, the field only needs to exist.
+type BodyMatch struct {
+	Path   string // dot separated json path, elements of arrays are indexed by number, e.g. user.ids.0
+	Equals string // the field equals the value, numbers and booleans are compared in json format
+	Regex  string // the field matches the regular expression
+	regex  *regexp.Regexp
+}
+
+// compile validates the conditions and compiles the regular expressions.
+func (m *Match) compile() error {
+	if m == nil {
+		return nil
+	}
+	for _, b := range m.Body {
+		if b == nil || b.Path == "" {
+			return errors.New("mock match: body condition requires a path")
+		}
+		if b.Regex == "" {
+			continue
+		}
+		regex, err := regexp.Compile(b.Regex)
+		if err != nil {
+			return fmt.Errorf("mock match: invalid regex of %s: %w", b.Path, err)
+		}
+		b.regex = regex
+	}
+	return nil
+}
+
+// match reports whether the request satisfies all the conditions, a nil Match matches all requests.
+func (m *Match) match(caller string, md codec.MetaData, req *request) bool {
+	if m == nil {
+		return true
+	}
+	if m.Caller != "" && m.Caller != caller {
+		return false
+	}
+	for k, v := range m.Metadata {
+		got, ok := md[k]
+		if !ok || (v != "" && v != string(got)) {
+			return false
+		}
+	}
+	if len(m.Body) == 0 {
+		return true
+	}
+	body, ok := req.json()
+	if !ok {
+		return false
+	}
+	for _, b := range m.Body {
+		if !b.match(body) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *BodyMatch) match(body interface{}) bool {
+	v, ok := lookup(body, b.Path)
+	if !ok {
+		return false
+	}
+	s := stringify(v)
+	if b.Equals != "" && b.Equals != s {
+		return false
+	}
+	if b.Regex == "" {
+		return true
+	}
+	if b.regex != nil {
+		return b.regex.MatchString(s)
+	}
+	matched, err := regexp.MatchString(b.Regex, s)
+	return err == nil && matched
+}
+
+// request lazily serializes the request as json, which is shared by all the mock items.
+type request struct {
+	body    interface{}
+	decoded bool
+	value   interface{}
+	err     error
+}
+
+func (r *request) json() (interface{}, bool) {
+	if !r.decoded {
+		r.decoded = true
+		r.value, r.err = decodeJSON(r.body)
+	}
+	return r.value, r.err == nil
+}
+
+// decodeJSON serializes the request as json, and decodes it into generic values.
+func decodeJSON(body interface{}) (interface{}, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if msg, ok := body.(proto.Message); ok {
+		data, err = codec.Marshaler.Marshal(msg)
+	} else {
+		data, err = codec.JSONAPI.Marshal(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// lookup returns the value of the dot separated path.
+func lookup(v interface{}, path string) (interface{}, bool) {
+	for _, name := range strings.Split(path, ".") {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			next, ok := value[name]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, false
+			}
+			v = value[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// stringify formats the value for comparison, strings are not quoted.
+func stringify(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	case nil:
+		return "null"
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
@@ -0,0 +1,147 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+	yaml "gopkg.in/yaml.v3"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/plugin"
+)
+
+type userReq struct {
+	User struct {
+		ID   int64    `json:"id"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	} `json:"user"`
+}
+
+func TestMatch(t *testing.T) {
+	req := &userReq{}
+	req.User.ID = 10001
+	req.User.Name = "alice"
+	req.User.Tags = []string{"vip"}
+	md := codec.MetaData{"env": []byte("test")}
+
+	tests := []struct {
+		match *Match
+		want  bool
+	}{
+		{match: nil, want: true},
+		{match: &Match{Caller: "trpc.app.caller.service"}, want: true},
+		{match: &Match{Caller: "trpc.app.other.service"}, want: false},
+		{match: &Match{Metadata: map[string]string{"env": "test"}}, want: true},
+		{match: &Match{Metadata: map[string]string{"env": ""}}, want: true},
+		{match: &Match{Metadata: map[string]string{"env": "prod"}}, want: false},
+		{match: &Match{Metadata: map[string]string{"uid": ""}}, want: false},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.id", Equals: "10001"}}}, want: true},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.id", Equals: "10002"}}}, want: false},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.name", Regex: "^al"}}}, want: true},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.tags.0", Equals: "vip"}}}, want: true},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.tags.1"}}}, want: false},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.name.first"}}}, want: false},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.tags", Equals: `["vip"]`}}}, want: true},
+		{match: &Match{Body: []*BodyMatch{{Path: "user.name", Regex: "("}}}, want: false},
+		{
+			match: &Match{
+				Caller:   "trpc.app.caller.service",
+				Metadata: map[string]string{"env": "test"},
+				Body:     []*BodyMatch{{Path: "user.id", Equals: "10001"}, {Path: "user.name", Equals: "bob"}},
+			},
+			want: false,
+		},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.want, tt.match.match("trpc.app.caller.service", md, &request{body: req}), i)
+	}
+
+	// Protobuf messages are serialized by protojson.
+	body, err := structpb.NewStruct(map[string]interface{}{"user_id": "u1"})
+	assert.Nil(t, err)
+	assert.True(t, (&Match{Body: []*BodyMatch{{Path: "user_id", Equals: "u1"}}}).match("", nil, &request{body: body}))
+}
+
+func TestMatch_Compile(t *testing.T) {
+	assert.Nil(t, (*Match)(nil).compile())
+	m := &Match{Body: []*BodyMatch{{Path: "user.name", Regex: "^al"}}}
+	assert.Nil(t, m.compile())
+	assert.NotNil(t, m.Body[0].regex)
+	assert.NotNil(t, (&Match{Body: []*BodyMatch{{Regex: "^al"}}}).compile())
+	assert.NotNil(t, (&Match{Body: []*BodyMatch{{Path: "user.name", Regex: "("}}}).compile())
+}
+
+func TestClientFilter_Match(t *testing.T) {
+	var node yaml.Node
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+- method: /trpc.app.server.service/GetUser
+  percent: 100
+  retcode: 1001
+  match:
+    body:
+      - path: user.id
+        equals: "10001"
+- method: /trpc.app.server.service/GetUser
+  percent: 100
+  retcode: 1002
+  match:
+    metadata:
+      env: test
+    body:
+      - path: user.name
+        regex: "^b"
+`), &node))
+	var conf Config
+	assert.Nil(t, node.Decode(&conf))
+	var opts []Option
+	for _, mock := range conf {
+		assert.Nil(t, mock.Match.compile())
+		opts = append(opts, WithMock(mock))
+	}
+	f := ClientFilter(opts...)
+
+	call := func(id int64, name string, md codec.MetaData) error {
+		ctx := trpc.BackgroundContext()
+		msg := trpc.Message(ctx)
+		msg.WithClientRPCName("/trpc.app.server.service/GetUser")
+		msg.WithClientMetaData(md)
+		req := &userReq{}
+		req.User.ID, req.User.Name = id, name
+		return f(ctx, req, nil, noopHandler)
+	}
+	assert.EqualValues(t, 1001, errs.Code(call(10001, "bob", nil)))
+	assert.EqualValues(t, 1002, errs.Code(call(10002, "bob", codec.MetaData{"env": []byte("test")})))
+	assert.Nil(t, call(10002, "bob", nil))
+	assert.Nil(t, call(10002, "alice", codec.MetaData{"env": []byte("test")}))
+
+	var p Plugin
+	assert.NotNil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, `
+- percent: 100
+  match:
+    body:
+      - path: user.name
+        regex: "("
+`)}))
+}
+
+func yamlNode(t *testing.T, conf string) *yaml.Node {
+	var node yaml.Node
+	assert.Nil(t, yaml.Unmarshal([]byte(conf), &node))
+	return &node
+}
@@ -16,9 +16,13 @@ package mock
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"math/rand"
 	"time"
 
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"trpc.group/trpc-go/trpc-go/codec"
 	"trpc.group/trpc-go/trpc-go/errs"
 	"trpc.group/trpc-go/trpc-go/filter"
@@ -28,6 +32,19 @@ import (
 
 type options struct {
 	mocks []*Item
+	store *Store
+}
+
+// items returns the mock items of the current call, the items of the store are matched first.
+func (o *options) items() []*Item {
+	if o.store == nil {
+		return o.mocks
+	}
+	items := o.store.Items()
+	if len(o.mocks) == 0 {
+		return items
+	}
+	return append(items[:len(items):len(items)], o.mocks...)
 }
 
 // Option set options.
@@ -40,6 +57,13 @@ func WithMock(mock *Item) Option {
 	}
 }
 
+// WithStore set the store of mock items which can be changed at runtime.
+func WithStore(store *Store) Option {
+	return func(opts *options) {
+		opts.store = store
+	}
+}
+
 // ClientFilter set client request mock interceptor.
 func ClientFilter(opts ...Option) filter.ClientFilter {
 	o := &options{}
@@ -51,33 +75,40 @@ func ClientFilter(opts ...Option) filter.ClientFilter {
 
 	return func(ctx context.Context, req, rsp interface{}, handler filter.ClientHandleFunc) error {
 		msg := trpc.Message(ctx)
+		r := &request{body: req}
 
-		for _, mock := range o.mocks {
+		for _, mock := range o.items() {
 			if mock.Method != "" && mock.Method != msg.ClientRPCName() {
 				continue
 			}
+			if !mock.Match.match(msg.CallerServiceName(), msg.ClientMetaData(), r) {
+				continue
+			}
+
+			if !mock.scheduled() {
+				continue
+			}
 
 			if mock.Percent == 0 || rand.Intn(100) >= mock.Percent {
 				// Triggered by percentage. For example, if 20%, the random number 0-99, only 0-19 will trigger.
 				continue
 			}
 
-			if mock.Timeout {
-				<-ctx.Done()
-				return errs.NewFrameError(errs.RetClientTimeout, "mock filter: timeout")
+			resp, calls := mock.next()
+			if err := resp.fault(ctx, msg, false); err != nil {
+				return err
 			}
-			if mock.Delay > 0 {
-				select {
-				case <-ctx.Done():
-					return errs.NewFrameError(errs.RetClientTimeout, "mock filter: timeout during delay mock")
-				case <-time.After(mock.delay):
-				}
-			}
-			if mock.Retcode > 0 {
-				return errs.New(mock.Retcode, mock.Retmsg)
+			if resp.Corrupt {
+				return resp.withPushback(msg, false, corruptResponse(mock.Serialization, rsp))
 			}
-			if mock.Body != "" {
-				if err := codec.Unmarshal(mock.Serialization, mock.data, rsp); err != nil {
+			if resp.Body != "" {
+				data, err := resp.body(mock.Serialization, func() *templateData {
+					return newTemplateData(msg.ClientRPCName(), msg.CallerServiceName(), msg.ClientMetaData(), r, calls)
+				})
+				if err != nil {
+					return errs.NewFrameError(errs.RetClientEncodeFail, "mock filter template: "+err.Error())
+				}
+				if err := codec.Unmarshal(mock.Serialization, data, rsp); err != nil {
 					return errs.NewFrameError(errs.RetClientDecodeFail, "mock filter Unmarshal: "+err.Error())
 				}
 				return nil
@@ -87,3 +118,84 @@ func ClientFilter(opts ...Option) filter.ClientFilter {
 		return handler(ctx, req, rsp)
 	}
 }
+
+// ServerFilter set server request mock interceptor, which stubs the handler of the matched ServerRPCName.
+// When the mock returns a response or an error, the handler is not called.
+func ServerFilter(opts ...Option) filter.ServerFilter {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	rand.Seed(time.Now().Unix())
+
+	return func(ctx context.Context, req interface{}, handler filter.ServerHandleFunc) (interface{}, error) {
+		msg := trpc.Message(ctx)
+		r := &request{body: req}
+
+		for _, mock := range o.items() {
+			if mock.Method != "" && mock.Method != msg.ServerRPCName() {
+				continue
+			}
+			if !mock.Match.match(msg.CallerServiceName(), msg.ServerMetaData(), r) {
+				continue
+			}
+
+			if !mock.scheduled() {
+				continue
+			}
+
+			if mock.Percent == 0 || rand.Intn(100) >= mock.Percent {
+				continue
+			}
+
+			resp, calls := mock.next()
+			if err := resp.fault(ctx, msg, true); err != nil {
+				return nil, err
+			}
+			// The response is encoded by the server in the serialization of the request.
+			serialization := msg.SerializationType()
+			if resp.Corrupt {
+				return corruptedResponse(serialization)
+			}
+			if resp.Body != "" {
+				if mock.Serialization != serialization {
+					return nil, errs.NewFrameError(errs.RetServerEncodeFail, fmt.Sprintf(
+						"mock filter: serialization %d of mock mismatches serialization %d of request",
+						mock.Serialization, serialization))
+				}
+				data, err := resp.body(serialization, func() *templateData {
+					return newTemplateData(msg.ServerRPCName(), msg.CallerServiceName(), msg.ServerMetaData(), r, calls)
+				})
+				if err != nil {
+					return nil, errs.NewFrameError(errs.RetServerEncodeFail, "mock filter template: "+err.Error())
+				}
+				rsp, err := rawResponse(serialization, data)
+				if err != nil {
+					return nil, errs.NewFrameError(errs.RetServerEncodeFail, "mock filter: "+err.Error())
+				}
+				return rsp, nil
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rawResponse wraps the serialized body as a response which is marshaled back to the same bytes by the server,
+// since the server filter does not know the concrete response type of the handler.
+func rawResponse(serialization int, data []byte) (interface{}, error) {
+	switch serialization {
+	case codec.SerializationTypePB:
+		// Unknown fields are marshaled as they are.
+		rsp := &emptypb.Empty{}
+		rsp.ProtoReflect().SetUnknown(protoreflect.RawFields(data))
+		return rsp, nil
+	case codec.SerializationTypeJSON:
+		return json.RawMessage(data), nil
+	case codec.SerializationTypeNoop:
+		return &codec.Body{Data: data}, nil
+	default:
+		return nil, fmt.Errorf("serialization %d is not supported by server mock", serialization)
+	}
+}
@@ -14,10 +14,11 @@
 package mock
 
 import (
-	"encoding/base64"
+	"sync"
+	"text/template"
 	"time"
 
-	"trpc.group/trpc-go/trpc-go/codec"
+	yaml "gopkg.in/yaml.v3"
 	"trpc.group/trpc-go/trpc-go/filter"
 	"trpc.group/trpc-go/trpc-go/plugin"
 )
@@ -32,7 +33,9 @@ func init() {
 }
 
 // Plugin mock trpc plugin implementation.
-type Plugin struct{}
+type Plugin struct {
+	watcher *dirWatcher
+}
 
 // Type mock trpc plugin type.
 func (p *Plugin) Type() string {
@@ -42,12 +45,30 @@ func (p *Plugin) Type() string {
 // Config mock plugin config.
 type Config []*Item
 
+// pluginConfig mock plugin config with options, the mocks can also be configured as a list of items directly.
+type pluginConfig struct {
+	Mocks   Config `yaml:"mocks"`
+	MockDir string `yaml:"mock_dir"` // directory of mock files
+	Watch   bool   `yaml:"watch"`    // reload the mock files on change
+	Admin   bool   `yaml:"admin"`    // register the admin api to change mocks at runtime
+}
+
+// UnmarshalYAML decodes the list of items or the config with options.
+func (c *pluginConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&c.Mocks)
+	}
+	type raw pluginConfig
+	return node.Decode((*raw)(c))
+}
+
 // MockItem specific mock items.
 // Deprecated: use Item instead.
 type MockItem = Item
 
 // Item Specific mock items
 type Item struct {
+	Name          string // name of the item added at runtime
 	Method        string
 	Retcode       int
 	Retmsg        string
@@ -58,31 +79,69 @@ type Item struct {
 	data          []byte
 	Serialization int // json jce pb
 	Percent       int
+	Match         *Match // request-matching conditions, the first matched item takes effect
+	Template      bool   // bodies are text/template executed for each call
+	template      *template.Template
+	Responses     []*Response // successive responses of the item, which override the response fields of the item
+	Mode          string      // selecting mode of Responses: sequence (default), round_robin or weighted
+	Latency       *Latency    // latency drawn from a distribution, which overrides Delay
+	Error         string      // connection-level error: net_err or connect_fail
+	Corrupt       bool        // return a corrupted response which cannot be decoded
+	Pushback      string      // pushback delay of slime retry returned with errors, e.g. 100ms, and negative for no retry
+	FailAfter     int         `yaml:"fail_after" json:"fail_after"`     // the first N matched calls are not affected
+	FailFirstN    int         `yaml:"fail_first_n" json:"fail_first_n"` // only N matched calls are affected (after FailAfter)
+
+	mu      sync.Mutex
+	calls   int // triggered calls
+	matched int // matched calls of the schedule
 }
 
 // Setup mock instance initialization.
 func (p *Plugin) Setup(name string, configDec plugin.Decoder) error {
-	conf := Config{}
+	conf := pluginConfig{}
 	if err := configDec.Decode(&conf); err != nil {
 		return err
 	}
 
-	var opt []Option
-	for _, mock := range conf {
-		mock.delay = time.Millisecond * time.Duration(mock.Delay)
-		if mock.Serialization != codec.SerializationTypeJSON {
-			// When the serialization method is not json, use base64 to decode.
-			decoded, err := base64.StdEncoding.DecodeString(mock.Body)
-			if err != nil {
-				return err
-			}
-			mock.data = decoded
-		} else {
-			mock.data = []byte(mock.Body)
+	for _, mock := range conf.Mocks {
+		if err := mock.setup(); err != nil {
+			return err
+		}
+	}
+	store := NewStore()
+	store.setConfig(conf.Mocks)
+	if conf.MockDir != "" {
+		items, err := loadDir(conf.MockDir)
+		if err != nil {
+			return err
 		}
-		opt = append(opt, WithMock(mock))
+		store.setFiles(items)
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if conf.MockDir != "" && conf.Watch {
+		w, err := watchDir(conf.MockDir, store)
+		if err != nil {
+			return err
+		}
+		p.watcher = w
+	}
+	DefaultStore = store
+	if conf.Admin {
+		registerAdmin(store)
 	}
 
-	filter.Register(pluginName, nil, ClientFilter(opt...))
+	filter.Register(pluginName, ServerFilter(WithStore(store)), ClientFilter(WithStore(store)))
 	return nil
 }
+
+// Close stops watching the mock files.
+func (p *Plugin) Close() error {
+	if p.watcher == nil {
+		return nil
+	}
+	err := p.watcher.close()
+	p.watcher = nil
+	return err
+}
@@ -22,6 +22,7 @@ import (
 	yaml "gopkg.in/yaml.v3"
 	"trpc.group/trpc-go/trpc-go"
 	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
 	"trpc.group/trpc-go/trpc-go/filter"
 	"trpc.group/trpc-go/trpc-go/plugin"
 )
@@ -115,3 +116,86 @@ func TestClientFilter(t *testing.T) {
 func noopHandler(ctx context.Context, req interface{}, rsp interface{}) error {
 	return nil
 }
+
+func TestServerFilter(t *testing.T) {
+	serialization := codec.SerializationTypeJSON
+	tests := []struct {
+		input     *Item
+		assertion assert.ValueAssertionFunc
+	}{
+		{
+			input:     &Item{Method: "method", Percent: 100, Retcode: 1},
+			assertion: assert.Nil,
+		},
+		{
+			input:     &Item{Timeout: true, Percent: 100},
+			assertion: assert.NotNil,
+		},
+		{
+			input:     &Item{Delay: 10, delay: 10 * time.Millisecond, Percent: 100},
+			assertion: assert.NotNil,
+		},
+		{
+			input:     &Item{Delay: 10, delay: time.Millisecond, Percent: 100},
+			assertion: assert.Nil,
+		},
+		{
+			input:     &Item{Retcode: 1, Percent: 100},
+			assertion: assert.NotNil,
+		},
+		{
+			input:     &Item{Body: "{}", Serialization: codec.SerializationTypeXML, data: []byte("{}"), Percent: 100},
+			assertion: assert.NotNil,
+		},
+		{
+			input:     &Item{Body: "{}", Serialization: serialization, data: []byte("{}"), Percent: 100},
+			assertion: assert.Nil,
+		},
+		{
+			input:     &Item{Percent: 0},
+			assertion: assert.Nil,
+		},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(trpc.BackgroundContext(), 2*time.Millisecond)
+		defer cancel()
+		trpc.Message(ctx).WithServerRPCName("/trpc.app.server.service/method")
+		trpc.Message(ctx).WithSerializationType(serialization)
+
+		fc := filter.ServerChain{ServerFilter(WithMock(tt.input))}
+
+		_, err := fc.Filter(ctx, &struct{}{}, noopServerHandler)
+		tt.assertion(t, err, err)
+	}
+}
+
+func TestServerFilter_Body(t *testing.T) {
+	for serialization, data := range map[int][]byte{
+		codec.SerializationTypePB:   {0x0a, 0x03, 'a', 'a', 'a'},
+		codec.SerializationTypeJSON: []byte(`{"a":"aaa"}`),
+		codec.SerializationTypeNoop: []byte("raw"),
+	} {
+		f := ServerFilter(WithMock(&Item{Body: "body", Serialization: serialization, data: data, Percent: 100}))
+		ctx := trpc.BackgroundContext()
+		trpc.Message(ctx).WithSerializationType(serialization)
+		rsp, err := f(ctx, nil, noopServerHandler)
+		assert.Nil(t, err)
+		// The response is marshaled by the server to the mock body.
+		body, err := codec.Marshal(serialization, rsp)
+		assert.Nil(t, err)
+		assert.Equal(t, data, body)
+	}
+
+	// The body can not be returned to a caller of another serialization, such as a json caller of a pb service.
+	f := ServerFilter(WithMock(&Item{
+		Body: "body", Serialization: codec.SerializationTypePB, data: []byte{0x0a, 0x03, 'a', 'a', 'a'}, Percent: 100,
+	}))
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithSerializationType(codec.SerializationTypeJSON)
+	_, err := f(ctx, nil, noopServerHandler)
+	assert.EqualValues(t, errs.RetServerEncodeFail, errs.Code(err))
+}
+
+func noopServerHandler(ctx context.Context, req interface{}) (interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,228 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"text/template"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+// Modes of selecting the successive responses of a mock item.
+const (
+	ModeSequence   = "sequence"    // return the responses in order, and keep returning the last one
+	ModeRoundRobin = "round_robin" // return the responses in order repeatedly
+	ModeWeighted   = "weighted"    // return a random response by weight
+)
+
+// Response a response of a mock item, which has the same semantics as the fields of Item.
+type Response struct {
+	Retcode  int
+	Retmsg   string
+	Delay    int
+	delay    time.Duration
+	Timeout  bool
+	Body     string
+	data     []byte
+	template *template.Template
+	Weight   int // weight in weighted mode
+
+	Latency  *Latency // latency drawn from a distribution, which overrides Delay
+	Error    string   // connection-level error: net_err or connect_fail
+	Corrupt  bool     // return a corrupted response which cannot be decoded
+	Pushback string   // pushback delay of slime retry returned with errors, e.g. 100ms, and negative for no retry
+}
+
+// templateData data referenced by the body templates.
+type templateData struct {
+	Method   string            // rpc name
+	Caller   string            // caller service name
+	Metadata map[string]string // metadata of the request
+	Request  interface{}       // request serialized as json, e.g. {{.Request.user.id}}
+	Count    int               // call counter of the mock item, starting at 1
+	Now      time.Time         // current time
+}
+
+var templateFuncs = template.FuncMap{
+	"uuid": randomID,
+	"randInt": func(min, max int) int {
+		if max <= min {
+			return min
+		}
+		return min + mrand.Intn(max-min)
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"unix":      func(t time.Time) int64 { return t.Unix() },
+	"unixMilli": func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) },
+}
+
+func newTemplateData(method, caller string, md codec.MetaData, req *request, calls int) *templateData {
+	metadata := make(map[string]string, len(md))
+	for k, v := range md {
+		metadata[k] = string(v)
+	}
+	body, _ := req.json()
+	return &templateData{
+		Method:   method,
+		Caller:   caller,
+		Metadata: metadata,
+		Request:  body,
+		Count:    calls,
+		Now:      time.Now(),
+	}
+}
+
+// randomID returns a random 32 hex characters id.
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// setup converts the config of the mock item.
+func (m *Item) setup() error {
+	m.delay = time.Millisecond * time.Duration(m.Delay)
+	if err := m.Match.compile(); err != nil {
+		return err
+	}
+	if err := m.response().setupFault(); err != nil {
+		return err
+	}
+	var err error
+	if m.data, m.template, err = m.parseBody(m.Body); err != nil {
+		return err
+	}
+	switch m.Mode {
+	case "", ModeSequence, ModeRoundRobin, ModeWeighted:
+	default:
+		return fmt.Errorf("mock: unknown mode %s of %s", m.Mode, m.Method)
+	}
+	for i, r := range m.Responses {
+		if r == nil {
+			return fmt.Errorf("mock: response %d of %s is empty", i, m.Method)
+		}
+		if m.Mode == ModeWeighted && r.Weight <= 0 {
+			return fmt.Errorf("mock: response %d of %s requires a positive weight", i, m.Method)
+		}
+		r.delay = time.Millisecond * time.Duration(r.Delay)
+		if err := r.setupFault(); err != nil {
+			return err
+		}
+		if r.data, r.template, err = m.parseBody(r.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseBody decodes the body, or parses it as a template when Template is set.
+func (m *Item) parseBody(body string) ([]byte, *template.Template, error) {
+	if body == "" {
+		return nil, nil, nil
+	}
+	if m.Template {
+		t, err := template.New(m.Method).Funcs(templateFuncs).Option("missingkey=zero").Parse(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mock: invalid template of %s: %w", m.Method, err)
+		}
+		return nil, t, nil
+	}
+	data, err := decodeBody(m.Serialization, body)
+	return data, nil, err
+}
+
+// decodeBody decodes the body of the serialization.
+func decodeBody(serialization int, body string) ([]byte, error) {
+	if serialization == codec.SerializationTypeJSON {
+		return []byte(body), nil
+	}
+	// When the serialization method is not json, use base64 to decode.
+	return base64.StdEncoding.DecodeString(body)
+}
+
+// next returns the response of the current call and the call counter.
+func (m *Item) next() (*Response, int) {
+	m.mu.Lock()
+	m.calls++
+	n := m.calls
+	m.mu.Unlock()
+
+	if len(m.Responses) == 0 {
+		return m.response(), n
+	}
+	switch m.Mode {
+	case ModeRoundRobin:
+		return m.Responses[(n-1)%len(m.Responses)], n
+	case ModeWeighted:
+		var total int
+		for _, r := range m.Responses {
+			total += r.Weight
+		}
+		w := mrand.Intn(total)
+		for _, r := range m.Responses {
+			if w < r.Weight {
+				return r, n
+			}
+			w -= r.Weight
+		}
+		return m.Responses[len(m.Responses)-1], n
+	default:
+		i := n - 1
+		if i >= len(m.Responses) {
+			i = len(m.Responses) - 1
+		}
+		return m.Responses[i], n
+	}
+}
+
+// response returns the response of the fields of the item.
+func (m *Item) response() *Response {
+	return &Response{
+		Retcode:  m.Retcode,
+		Retmsg:   m.Retmsg,
+		Delay:    m.Delay,
+		delay:    m.delay,
+		Timeout:  m.Timeout,
+		Body:     m.Body,
+		data:     m.data,
+		template: m.template,
+		Latency:  m.Latency,
+		Error:    m.Error,
+		Corrupt:  m.Corrupt,
+		Pushback: m.Pushback,
+	}
+}
+
+// body returns the serialized body of the response, and executes the template with the data.
+func (r *Response) body(serialization int, data func() *templateData) ([]byte, error) {
+	if r.template == nil {
+		return r.data, nil
+	}
+	var buf bytes.Buffer
+	if err := r.template.Execute(&buf, data()); err != nil {
+		return nil, err
+	}
+	return decodeBody(serialization, buf.String())
+}
@@ -0,0 +1,163 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/plugin"
+)
+
+func TestItem_Sequence(t *testing.T) {
+	mock := &Item{Percent: 100, Serialization: codec.SerializationTypeJSON, Responses: []*Response{
+		{Body: `{"name":"a"}`},
+		{Body: `{"name":"b"}`},
+		{Retcode: 1001, Retmsg: "error"},
+	}}
+	assert.Nil(t, mock.setup())
+	f := ClientFilter(WithMock(mock))
+	call := func() (string, error) {
+		rsp := &struct {
+			Name string `json:"name"`
+		}{}
+		err := f(trpc.BackgroundContext(), nil, rsp, noopHandler)
+		return rsp.Name, err
+	}
+	for _, want := range []string{"a", "b"} {
+		name, err := call()
+		assert.Nil(t, err)
+		assert.Equal(t, want, name)
+	}
+	// The last response is kept.
+	for i := 0; i < 2; i++ {
+		_, err := call()
+		assert.EqualValues(t, 1001, errs.Code(err))
+	}
+}
+
+func TestItem_RoundRobin(t *testing.T) {
+	mock := &Item{Mode: ModeRoundRobin, Responses: []*Response{{Retcode: 1}, {Retcode: 2}}}
+	assert.Nil(t, mock.setup())
+	for _, want := range []int{1, 2, 1, 2} {
+		r, _ := mock.next()
+		assert.Equal(t, want, r.Retcode)
+	}
+}
+
+func TestItem_Weighted(t *testing.T) {
+	mock := &Item{Mode: ModeWeighted, Responses: []*Response{{Retcode: 1, Weight: 3}, {Retcode: 2, Weight: 1}}}
+	assert.Nil(t, mock.setup())
+	counts := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		r, n := mock.next()
+		assert.Equal(t, i+1, n)
+		counts[r.Retcode]++
+	}
+	assert.Equal(t, 1000, counts[1]+counts[2])
+	assert.Greater(t, counts[1], counts[2])
+}
+
+func TestItem_Template(t *testing.T) {
+	mock := &Item{
+		Percent:       100,
+		Serialization: codec.SerializationTypeJSON,
+		Template:      true,
+		Body: `{"id":{{.Request.user.id}},"name":{{json .Request.user.name}},"count":{{.Count}},` +
+			`"env":"{{.Metadata.env}}","trace":"{{uuid}}","time":{{unix .Now}},"method":"{{.Method}}"}`,
+	}
+	assert.Nil(t, mock.setup())
+	f := ServerFilter(WithMock(mock))
+	ctx := trpc.BackgroundContext()
+	msg := trpc.Message(ctx)
+	msg.WithServerRPCName("/trpc.app.server.service/GetUser")
+	msg.WithServerMetaData(codec.MetaData{"env": []byte("test")})
+	msg.WithSerializationType(codec.SerializationTypeJSON)
+	req := &userReq{}
+	req.User.ID, req.User.Name = 10001, `"alice"`
+
+	var traces []string
+	for i := 1; i <= 2; i++ {
+		rsp, err := f(ctx, req, noopServerHandler)
+		assert.Nil(t, err)
+		var body struct {
+			ID     int64
+			Name   string
+			Count  int
+			Env    string
+			Trace  string
+			Time   int64
+			Method string
+		}
+		assert.Nil(t, json.Unmarshal(rsp.(json.RawMessage), &body))
+		assert.Equal(t, int64(10001), body.ID)
+		assert.Equal(t, `"alice"`, body.Name)
+		assert.Equal(t, i, body.Count)
+		assert.Equal(t, "test", body.Env)
+		assert.Len(t, body.Trace, 32)
+		assert.NotZero(t, body.Time)
+		assert.Equal(t, "/trpc.app.server.service/GetUser", body.Method)
+		traces = append(traces, body.Trace)
+	}
+	assert.NotEqual(t, traces[0], traces[1])
+
+	// Errors of executing templates are returned.
+	mock = &Item{Percent: 100, Serialization: codec.SerializationTypeJSON, Template: true, Body: `{{index .Request 1}}`}
+	assert.Nil(t, mock.setup())
+	_, err := ServerFilter(WithMock(mock))(ctx, req, noopServerHandler)
+	assert.EqualValues(t, errs.RetServerEncodeFail, errs.Code(err))
+}
+
+func TestItem_Setup(t *testing.T) {
+	for _, conf := range []string{`
+- body: '{{.Count'
+  template: true
+  serialization: 2
+`, `
+- mode: random
+  responses:
+    - retcode: 1
+`, `
+- mode: weighted
+  responses:
+    - retcode: 1
+`, `
+- responses:
+    - body: not base64
+`, `
+- responses:
+    -
+`} {
+		var p Plugin
+		assert.NotNil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, conf)}), conf)
+	}
+
+	var p Plugin
+	assert.Nil(t, p.Setup(pluginName, &plugin.YamlNodeDecoder{Node: yamlNode(t, `
+- method: /trpc.app.server.service/GetUser
+  percent: 100
+  serialization: 2
+  template: true
+  mode: round_robin
+  responses:
+    - body: '{"id":{{.Request.id}}}'
+      delay: 10
+    - retcode: 1001
+      retmsg: "error"
+`)}))
+}
@@ -0,0 +1,114 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"errors"
+	"sync"
+)
+
+// Store mock items which can be changed at runtime.
+// The filters created with WithStore use the latest items of the store for each call.
+type Store struct {
+	mu       sync.RWMutex
+	admin    []*Item // added at runtime, matched first
+	files    []*Item // loaded from mock_dir
+	config   []*Item // from the plugin config
+	snapshot []*Item
+}
+
+// DefaultStore the store of the mock plugin, which is set up by the plugin.
+var DefaultStore = NewStore()
+
+// NewStore creates a store.
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Items returns the items of the store in matching order:
+// items added at runtime, items loaded from files, and then items of the plugin config.
+// The returned slice must not be modified.
+func (s *Store) Items() []*Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+// Add adds a mock item with a name at runtime, which replaces the item of the same name.
+func (s *Store) Add(item *Item) error {
+	if item == nil || item.Name == "" {
+		return errors.New("mock: name of the item is required")
+	}
+	if err := item.setup(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	admin := make([]*Item, 0, len(s.admin)+1)
+	for _, m := range s.admin {
+		if m.Name != item.Name {
+			admin = append(admin, m)
+		}
+	}
+	s.admin = append(admin, item)
+	s.update()
+	return nil
+}
+
+// Remove removes the item added at runtime by name, and reports whether it exists.
+func (s *Store) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.admin {
+		if m.Name == name {
+			s.admin = append(s.admin[:i:i], s.admin[i+1:]...)
+			s.update()
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the items of the store by source.
+func (s *Store) List() map[string][]*Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string][]*Item{
+		"admin":  s.admin,
+		"files":  s.files,
+		"config": s.config,
+	}
+}
+
+func (s *Store) setConfig(items []*Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = items
+	s.update()
+}
+
+func (s *Store) setFiles(items []*Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = items
+	s.update()
+}
+
+// update rebuilds the snapshot, which is replaced instead of modified.
+func (s *Store) update() {
+	snapshot := make([]*Item, 0, len(s.admin)+len(s.files)+len(s.config))
+	snapshot = append(snapshot, s.admin...)
+	snapshot = append(snapshot, s.files...)
+	s.snapshot = append(snapshot, s.config...)
+}
@@ -0,0 +1,53 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"trpc.group/trpc-go/trpc-go"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	s.setConfig([]*Item{{Method: "/a", Percent: 100, Retcode: 3}})
+	s.setFiles([]*Item{{Method: "/a", Percent: 100, Retcode: 2}})
+	assert.NotNil(t, s.Add(&Item{Method: "/a"}))
+	assert.NotNil(t, s.Add(&Item{Name: "bad", Mode: "unknown"}))
+	assert.Nil(t, s.Add(&Item{Name: "a", Method: "/a", Percent: 100, Retcode: 1}))
+
+	f := ClientFilter(WithStore(s), WithMock(&Item{Method: "/a", Percent: 100, Retcode: 4}))
+	ctx := trpc.BackgroundContext()
+	trpc.Message(ctx).WithClientRPCName("/a")
+	code := func() int {
+		return int(errs.Code(f(ctx, nil, nil, noopHandler)))
+	}
+	assert.Equal(t, 1, code())
+
+	// Items of the same name are replaced.
+	assert.Nil(t, s.Add(&Item{Name: "a", Method: "/a", Percent: 100, Retcode: 5}))
+	assert.Equal(t, 5, code())
+	assert.Len(t, s.List()["admin"], 1)
+
+	assert.True(t, s.Remove("a"))
+	assert.False(t, s.Remove("a"))
+	assert.Equal(t, 2, code())
+	s.setFiles(nil)
+	assert.Equal(t, 3, code())
+	s.setConfig(nil)
+	assert.Equal(t, 4, code())
+	assert.Len(t, s.Items(), 0)
+}
@@ -11,7 +11,7 @@
 //
 //
 
-// Package recovery is a tRPC filter used to recover the server side from a panic.
+// Package recovery is a tRPC filter used to recover the server and client side from a panic.
 package recovery
 
 import (
@@ -26,9 +26,14 @@ import (
 )
 
 func init() {
-	filter.Register("recovery", ServerFilter(), nil)
+	filter.Register("recovery", ServerFilter(), ClientFilter())
 }
 
+// RetClientSystemErr is the error code of the client panics recovered by the client filter.
+// It is RetUnknown since trpc-go has no system error code for the client side,
+// use WithRecoveryHandler or the client_code of the plugin config to return another code.
+const RetClientSystemErr = errs.RetUnknown
+
 // PanicBufLen is the size of the buffer for storing the panic call stack log. The default value as below.
 var PanicBufLen = 4096
 
@@ -54,30 +59,80 @@ func WithRecoveryHandler(rh Handler) Option {
 }
 
 var defaultRecoveryHandler = func(ctx context.Context, e interface{}) error {
+	report(ctx, e)
+	return errs.NewFrameError(errs.RetServerSystemErr, fmt.Sprint(e))
+}
+
+var defaultClientRecoveryHandler = func(ctx context.Context, e interface{}) error {
+	report(ctx, e)
+	return errs.NewFrameError(RetClientSystemErr, fmt.Sprint(e))
+}
+
+// report logs the panic with the call stack, and reports the panic metrics.
+func report(ctx context.Context, e interface{}) {
 	buf := make([]byte, PanicBufLen)
 	buf = buf[:runtime.Stack(buf, false)]
 	log.ErrorContextf(ctx, "[PANIC]%v\n%s\n", e, buf)
 	metrics.IncrCounter("trpc.PanicNum", 1)
-	return errs.NewFrameError(errs.RetServerSystemErr, fmt.Sprint(e))
 }
 
 var defaultOptions = &options{
 	rh: defaultRecoveryHandler,
 }
 
-// ServerFilter adds the recovery filter to the server.
-func ServerFilter(opts ...Option) filter.ServerFilter {
-	o := defaultOptions
+var defaultClientOptions = &options{
+	rh: defaultClientRecoveryHandler,
+}
+
+// newOptions applies opts to a copy of the default options.
+func newOptions(defaults *options, opts []Option) *options {
+	o := *defaults
 	for _, opt := range opts {
-		opt(o)
+		opt(&o)
 	}
+	return &o
+}
+
+// ServerFilter adds the recovery filter to the server.
+func ServerFilter(opts ...Option) filter.ServerFilter {
+	o := newOptions(defaultOptions, opts)
 	return func(ctx context.Context, req interface{}, handler filter.ServerHandleFunc) (rsp interface{}, err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				err = o.rh(ctx, r)
+				err = o.rh(withPanicInfo(ctx, SideServer, req), r)
 			}
 		}()
 
 		return handler(ctx, req)
 	}
 }
+
+// ClientFilter adds the recovery filter to the client, which recovers the panics of the subsequent client filters,
+// codecs and transports, and returns RetClientSystemErr by default.
+func ClientFilter(opts ...Option) filter.ClientFilter {
+	o := newOptions(defaultClientOptions, opts)
+	return func(ctx context.Context, req, rsp interface{}, handler filter.ClientHandleFunc) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = o.rh(withPanicInfo(ctx, SideClient, req), r)
+			}
+		}()
+
+		return handler(ctx, req, rsp)
+	}
+}
+
+// Go runs fn in a new goroutine, and recovers its panic with the recovery handler,
+// which logs the panic and reports the metrics like the server filter, so that the panic does not crash the process.
+func Go(ctx context.Context, fn func(ctx context.Context), opts ...Option) {
+	o := newOptions(defaultOptions, opts)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				_ = o.rh(withPanicInfo(ctx, SideGoroutine, nil), r)
+			}
+		}()
+
+		fn(ctx)
+	}()
+}
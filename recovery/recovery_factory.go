@@ -0,0 +1,112 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package recovery
+
+import (
+	"errors"
+
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/filter"
+	"trpc.group/trpc-go/trpc-go/plugin"
+)
+
+const (
+	pluginName = "recovery"
+	pluginType = "tracing"
+)
+
+func init() {
+	plugin.Register(pluginName, &Plugin{})
+}
+
+// Config is the config of the recovery plugin.
+type Config struct {
+	// StackDepth is the number of frames of the reported stack, 32 by default.
+	StackDepth int `yaml:"stack_depth"`
+	// IncludeRequest reports the request as json with the sensitive fields redacted.
+	IncludeRequest bool `yaml:"include_request"`
+	// IncludeMetadata reports the metadata with the sensitive values redacted.
+	IncludeMetadata bool `yaml:"include_metadata"`
+	// RedactKeys are the keys of request fields and metadata to redact besides the defaults,
+	// which match the keys containing them case-insensitively.
+	RedactKeys []string `yaml:"redact_keys"`
+	// MaxRequestLen truncates the reported request, 1024 by default.
+	MaxRequestLen int `yaml:"max_request_len"`
+	// Code is the error code returned by the server filter, RetServerSystemErr by default.
+	Code int `yaml:"code"`
+	// ClientCode is the error code returned by the client filter, RetClientSystemErr by default.
+	ClientCode int `yaml:"client_code"`
+	// Message is the error message returned instead of the panic value, which hides the details from callers.
+	Message string `yaml:"message"`
+	// Dedup deduplicates the reports of the same stack signature.
+	Dedup DedupConfig `yaml:"dedup"`
+}
+
+// DedupConfig is the config of deduplicating the reports of the same stack signature.
+type DedupConfig struct {
+	// Interval is the window in seconds.
+	Interval int `yaml:"interval"`
+	// Burst is the number of the reports of each signature in a window, 0 disables deduplication.
+	Burst int `yaml:"burst"`
+}
+
+// DefaultReporter is the reporter of the recovery plugin, which is nil if the plugin is not set up.
+var DefaultReporter *Reporter
+
+// Stats returns the statistics of the panics by signature of the recovery plugin.
+func Stats() []SignatureStats {
+	if DefaultReporter == nil {
+		return nil
+	}
+	return DefaultReporter.Stats()
+}
+
+// Plugin is the recovery plugin, which reports the panics recovered by the filters and Go as structured reports.
+type Plugin struct{}
+
+// Type returns the plugin type.
+func (p *Plugin) Type() string {
+	return pluginType
+}
+
+// Setup registers the recovery filters which report the panics by the config.
+// The filters and Go created by ServerFilter, ClientFilter and Go in code keep the default handlers.
+func (p *Plugin) Setup(_ string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("recovery: config decoder empty")
+	}
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	if cfg.StackDepth < 0 || cfg.MaxRequestLen < 0 || cfg.Dedup.Interval < 0 || cfg.Dedup.Burst < 0 {
+		return errors.New("recovery: negative config")
+	}
+	if cfg.Dedup.Burst > 0 && cfg.Dedup.Interval == 0 {
+		return errors.New("recovery: dedup interval is required")
+	}
+	if cfg.Code == 0 {
+		cfg.Code = int(errs.RetServerSystemErr)
+	}
+	if cfg.ClientCode == 0 {
+		cfg.ClientCode = int(RetClientSystemErr)
+	}
+
+	r := NewReporter(cfg)
+	DefaultReporter = r
+	filter.Register(pluginName,
+		ServerFilter(WithRecoveryHandler(r.Handler(cfg.Code))),
+		ClientFilter(WithRecoveryHandler(r.Handler(cfg.ClientCode))))
+	return nil
+}
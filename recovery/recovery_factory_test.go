@@ -0,0 +1,104 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package recovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	yaml "gopkg.in/yaml.v3"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/filter"
+	"trpc.group/trpc-go/trpc-go/plugin"
+)
+
+func setupPlugin(conf string) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(conf), &node); err != nil {
+		return err
+	}
+	return (&Plugin{}).Setup(pluginName, &plugin.YamlNodeDecoder{Node: &node})
+}
+
+func TestPlugin_Setup(t *testing.T) {
+	Convey("TestPlugin_Setup", t, func() {
+		defer func() {
+			DefaultReporter = nil
+			filter.Register(pluginName, ServerFilter(), ClientFilter())
+		}()
+		So((&Plugin{}).Type(), ShouldEqual, pluginType)
+		So(Stats(), ShouldBeNil)
+		done := make(chan struct{})
+		DefaultReporter.Go(context.Background(), func(ctx context.Context) {
+			defer close(done)
+			panic("something wrong")
+		})
+		<-done
+
+		So(setupPlugin(`
+stack_depth: 16
+include_request: true
+include_metadata: true
+redact_keys: [phone]
+code: 10001
+client_code: 10002
+message: internal error
+dedup:
+  interval: 60
+  burst: 1
+`), ShouldBeNil)
+		ctx := context.Background()
+		fail := func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("something wrong")
+		}
+		_, err := filter.GetServer(pluginName)(ctx, nil, fail)
+		So(errs.Code(err), ShouldEqual, 10001)
+		So(errs.Msg(err), ShouldEqual, "internal error")
+		// The filters created in code are not changed by the plugin.
+		_, err = ServerFilter()(ctx, nil, fail)
+		So(errs.Code(err), ShouldEqual, errs.RetServerSystemErr)
+		So(errs.Msg(err), ShouldEqual, "something wrong")
+		err = filter.GetClient(pluginName)(ctx, nil, nil, func(ctx context.Context, req, rsp interface{}) error {
+			panic("something wrong")
+		})
+		So(errs.Code(err), ShouldEqual, 10002)
+
+		DefaultReporter.Go(ctx, func(ctx context.Context) {
+			panic("something wrong")
+		})
+		count := func() uint64 {
+			var count uint64
+			for _, s := range Stats() {
+				count += s.Count
+			}
+			return count
+		}
+		// The panic of the goroutine is reported after fn returns.
+		for deadline := time.Now().Add(time.Second); count() < 3 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		So(count(), ShouldEqual, 3)
+
+		So(setupPlugin(`stack_depth: -1`), ShouldNotBeNil)
+		So(setupPlugin(`dedup: {burst: 1}`), ShouldNotBeNil)
+		So((&Plugin{}).Setup(pluginName, nil), ShouldNotBeNil)
+
+		So(setupPlugin(`{}`), ShouldBeNil)
+		_, err = filter.GetServer(pluginName)(ctx, nil, fail)
+		So(errs.Code(err), ShouldEqual, errs.RetServerSystemErr)
+		So(errs.Msg(err), ShouldEqual, "something wrong")
+	})
+}
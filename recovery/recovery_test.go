@@ -15,9 +15,11 @@ package recovery
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"trpc.group/trpc-go/trpc-go/errs"
 )
 
 func TestServerFilter(t *testing.T) {
@@ -58,3 +60,55 @@ func TestWithRecoveryHandler(t *testing.T) {
 		assertHandlerEquality(opts.rh, defaultRecoveryHandler)
 	})
 }
+
+func TestClientFilter(t *testing.T) {
+	Convey("TestClientFilter", t, func() {
+		succHandler := func(ctx context.Context, req, rsp interface{}) error {
+			return nil
+		}
+		failHandler := func(ctx context.Context, req, rsp interface{}) error {
+			panic("something wrong")
+		}
+		ctx := context.Background()
+		Convey("test succ", func() {
+			So(ClientFilter()(ctx, nil, nil, succHandler), ShouldBeNil)
+		})
+		Convey("test fail", func() {
+			err := ClientFilter()(ctx, nil, nil, failHandler)
+			So(errs.Code(err), ShouldEqual, RetClientSystemErr)
+			So(err, ShouldResemble, defaultClientRecoveryHandler(ctx, "something wrong"))
+		})
+		Convey("test handler", func() {
+			err := ClientFilter(WithRecoveryHandler(func(ctx context.Context, e interface{}) error {
+				return errs.New(1001, fmt.Sprint(e))
+			}))(ctx, nil, nil, failHandler)
+			So(errs.Code(err), ShouldEqual, 1001)
+			// The default options are not modified.
+			_, err = ServerFilter()(ctx, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+				panic("something wrong")
+			})
+			So(errs.Code(err), ShouldEqual, errs.RetServerSystemErr)
+		})
+	})
+}
+
+func TestGo(t *testing.T) {
+	Convey("TestGo", t, func() {
+		ctx := context.Background()
+		recovered := make(chan interface{}, 1)
+		Go(ctx, func(ctx context.Context) {
+			panic("something wrong")
+		}, WithRecoveryHandler(func(ctx context.Context, e interface{}) error {
+			recovered <- e
+			return defaultRecoveryHandler(ctx, e)
+		}))
+		So(<-recovered, ShouldEqual, "something wrong")
+
+		done := make(chan struct{})
+		Go(ctx, func(ctx context.Context) {
+			defer close(done)
+			panic("something wrong")
+		})
+		<-done
+	})
+}
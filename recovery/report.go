@@ -0,0 +1,368 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package recovery
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/log"
+	"trpc.group/trpc-go/trpc-go/metrics"
+)
+
+// Sides where the panics are recovered.
+const (
+	SideServer    = "server"
+	SideClient    = "client"
+	SideGoroutine = "goroutine"
+)
+
+const (
+	defaultStackDepth    = 32
+	defaultMaxRequestLen = 1024
+	maxMetadataValueLen  = 256
+	// maxSignatures limits the signatures tracked for deduplication,
+	// the panics of new signatures beyond the limit share one signature.
+	maxSignatures     = 1024
+	overflowSignature = "overflow"
+	redacted          = "******"
+)
+
+// defaultRedactKeys are the keys of request fields and metadata always redacted,
+// which match the keys containing them case-insensitively.
+var defaultRedactKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie"}
+
+// Report is the structured report of a panic.
+type Report struct {
+	// Signature identifies the panic site by the stack, the panics of the same signature are deduplicated.
+	Signature string `json:"signature"`
+	Panic     string `json:"panic"`
+	Side      string `json:"side"`
+	RPCName   string `json:"rpc_name,omitempty"`
+	Caller    string `json:"caller,omitempty"`
+	Callee    string `json:"callee,omitempty"`
+	// Request is the json of the request with the sensitive fields redacted.
+	Request string `json:"request,omitempty"`
+	// Metadata is the metadata of the request with the sensitive values redacted.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Stack is the frames of the panic site, formatted as "function file:line".
+	Stack []string `json:"stack"`
+	// Count is the total number of the panics of the signature.
+	Count uint64 `json:"count"`
+	// Suppressed is the number of the panics of the signature not reported since the last report.
+	Suppressed uint64 `json:"suppressed,omitempty"`
+}
+
+// SignatureStats is the statistics of the panics of a signature.
+type SignatureStats struct {
+	Signature  string    `json:"signature"`
+	Panic      string    `json:"panic"` // the last panic value
+	Count      uint64    `json:"count"`
+	Suppressed uint64    `json:"suppressed"` // the total number of the panics not reported
+	First      time.Time `json:"first"`
+	Last       time.Time `json:"last"`
+}
+
+// Reporter reports the panics as structured reports, and deduplicates the reports of the same signature.
+type Reporter struct {
+	cfg        Config
+	redactKeys []string
+
+	mu         sync.Mutex
+	signatures map[string]*signatureState
+}
+
+type signatureState struct {
+	SignatureStats
+	window     time.Time // start of the current deduplication window
+	reported   int       // reports in the current window
+	suppressed uint64    // panics not reported since the last report
+}
+
+// NewReporter creates a reporter with the config.
+func NewReporter(cfg Config) *Reporter {
+	if cfg.StackDepth <= 0 {
+		cfg.StackDepth = defaultStackDepth
+	}
+	if cfg.MaxRequestLen <= 0 {
+		cfg.MaxRequestLen = defaultMaxRequestLen
+	}
+	r := &Reporter{cfg: cfg, signatures: make(map[string]*signatureState)}
+	for _, k := range append(defaultRedactKeys, cfg.RedactKeys...) {
+		r.redactKeys = append(r.redactKeys, normalizeKey(k))
+	}
+	return r
+}
+
+// Handler returns the recovery handler which reports the panic, and returns the framework error with the code.
+// The configured message is returned instead of the panic value if set.
+func (r *Reporter) Handler(code int) Handler {
+	return func(ctx context.Context, e interface{}) error {
+		r.Report(ctx, e)
+		msg := r.cfg.Message
+		if msg == "" {
+			msg = fmt.Sprint(e)
+		}
+		return errs.NewFrameError(code, msg)
+	}
+}
+
+// Go runs fn in a new goroutine like Go, and reports its panic by the reporter.
+// A nil reporter uses the default handler, so DefaultReporter.Go works before the plugin is set up.
+func (r *Reporter) Go(ctx context.Context, fn func(ctx context.Context), opts ...Option) {
+	if r == nil {
+		Go(ctx, fn, opts...)
+		return
+	}
+	Go(ctx, fn, append([]Option{WithRecoveryHandler(r.Handler(r.cfg.Code))}, opts...)...)
+}
+
+// Report reports the panic e recovered in the deferred function, it must be called by the recovery handler.
+func (r *Reporter) Report(ctx context.Context, e interface{}) {
+	metrics.IncrCounter("trpc.PanicNum", 1)
+	rep := r.newReport(ctx, e)
+	if rep == nil {
+		return
+	}
+	b, err := json.Marshal(rep)
+	if err != nil {
+		log.ErrorContextf(ctx, "[PANIC]%v\n%s\n", e, strings.Join(rep.Stack, "\n"))
+		return
+	}
+	log.ErrorContextf(ctx, "[PANIC]%s", b)
+}
+
+// newReport returns the report of the panic, or nil if the report is suppressed by deduplication.
+func (r *Reporter) newReport(ctx context.Context, e interface{}) *Report {
+	rep := &Report{Panic: fmt.Sprint(e), Side: SideServer}
+	rep.Stack = panicStack(r.cfg.StackDepth)
+	rep.Signature = signature(rep.Stack)
+	if !r.dedup(rep) {
+		return nil
+	}
+
+	info, _ := ctx.Value(panicInfoKey{}).(*panicInfo)
+	if info != nil {
+		rep.Side = info.side
+	}
+	msg := codec.Message(ctx)
+	switch rep.Side {
+	case SideServer:
+		rep.RPCName, rep.Caller, rep.Callee = msg.ServerRPCName(), msg.CallerServiceName(), msg.CalleeServiceName()
+		if r.cfg.IncludeMetadata {
+			rep.Metadata = r.redactMetadata(msg.ServerMetaData())
+		}
+	case SideClient:
+		rep.RPCName, rep.Caller, rep.Callee = msg.ClientRPCName(), msg.CallerServiceName(), msg.CalleeServiceName()
+		if r.cfg.IncludeMetadata {
+			rep.Metadata = r.redactMetadata(msg.ClientMetaData())
+		}
+	}
+	if r.cfg.IncludeRequest && info != nil && info.req != nil {
+		rep.Request = r.redactRequest(info.req)
+	}
+	return rep
+}
+
+// dedup records the panic of the report, and reports whether it should be reported.
+func (r *Reporter) dedup(rep *Report) bool {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.signatures[rep.Signature]
+	if !ok {
+		if len(r.signatures) >= maxSignatures {
+			rep.Signature = overflowSignature
+			s = r.signatures[overflowSignature]
+		}
+		if s == nil {
+			s = &signatureState{SignatureStats: SignatureStats{Signature: rep.Signature, First: now}, window: now}
+			r.signatures[rep.Signature] = s
+		}
+	}
+	s.Count++
+	s.Panic, s.Last = rep.Panic, now
+	rep.Count = s.Count
+
+	d := r.cfg.Dedup
+	if d.Burst <= 0 {
+		return true
+	}
+	if interval := time.Duration(d.Interval) * time.Second; now.Sub(s.window) >= interval {
+		s.window, s.reported = now, 0
+	}
+	if s.reported >= d.Burst {
+		s.suppressed++
+		s.Suppressed++
+		return false
+	}
+	s.reported++
+	rep.Suppressed, s.suppressed = s.suppressed, 0
+	return true
+}
+
+// Stats returns the statistics of the panics by signature, sorted by count in descending order.
+func (r *Reporter) Stats() []SignatureStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := make([]SignatureStats, 0, len(r.signatures))
+	for _, s := range r.signatures {
+		stats = append(stats, s.SignatureStats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Signature < stats[j].Signature
+	})
+	return stats
+}
+
+// panicStack returns at most depth frames of the panic site.
+// The frames of the recovery handler and the runtime panic are skipped.
+func panicStack(depth int) []string {
+	pcs := make([]uintptr, depth+64)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	frames := runtime.CallersFrames(pcs)
+	var stack []string
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.gopanic" {
+			// Frames after the runtime panic are the panic site, the previous ones are the handler.
+			stack = stack[:0]
+		} else {
+			stack = append(stack, fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	if len(stack) > depth {
+		stack = stack[:depth]
+	}
+	return stack
+}
+
+// signature identifies the stack.
+func signature(stack []string) string {
+	h := sha1.New()
+	for _, frame := range stack {
+		// The functions and lines identify the panic site, regardless of the file paths of builds.
+		if i := strings.IndexByte(frame, ' '); i >= 0 {
+			h.Write([]byte(frame[:i]))
+		}
+		if i := strings.LastIndexByte(frame, ':'); i >= 0 {
+			h.Write([]byte(frame[i:]))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func (r *Reporter) redactMetadata(md codec.MetaData) map[string]string {
+	if len(md) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(md))
+	for k, v := range md {
+		if r.sensitive(k) {
+			m[k] = redacted
+			continue
+		}
+		m[k] = truncate(string(v), maxMetadataValueLen)
+	}
+	return m
+}
+
+// redactRequest returns the json of the request with the sensitive fields redacted.
+func (r *Reporter) redactRequest(req interface{}) string {
+	b, err := codec.JSONAPI.Marshal(req)
+	if err != nil {
+		return fmt.Sprintf("<%T: %v>", req, err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Sprintf("<%T: %v>", req, err)
+	}
+	if b, err = json.Marshal(r.redact(v)); err != nil {
+		return fmt.Sprintf("<%T: %v>", req, err)
+	}
+	return truncate(string(b), r.cfg.MaxRequestLen)
+}
+
+func (r *Reporter) redact(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			if r.sensitive(k) {
+				value[k] = redacted
+				continue
+			}
+			value[k] = r.redact(field)
+		}
+	case []interface{}:
+		for i, elem := range value {
+			value[i] = r.redact(elem)
+		}
+	}
+	return v
+}
+
+// sensitive reports whether the key contains any of the redact keys.
+func (r *Reporter) sensitive(key string) bool {
+	key = normalizeKey(key)
+	for _, k := range r.redactKeys {
+		if k != "" && strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeKey converts the key to lower case and removes the separators.
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(key))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "...(truncated)"
+}
+
+// panicInfo is the information of the recovered panic passed to the recovery handler.
+type panicInfo struct {
+	side string
+	req  interface{}
+}
+
+type panicInfoKey struct{}
+
+// withPanicInfo returns the context with the panic information, which is only called on panics.
+func withPanicInfo(ctx context.Context, side string, req interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, panicInfoKey{}, &panicInfo{side: side, req: req})
+}
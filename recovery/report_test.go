@@ -0,0 +1,128 @@
+//
+//
+// Tencent is pleased to support the open source community by making tRPC available.
+//
+// Copyright (C) 2023 Tencent.
+// All rights reserved.
+//
+// If you have downloaded a copy of the tRPC source code from Tencent,
+// please note that tRPC source code is licensed under the Apache 2.0 License,
+// A copy of the Apache 2.0 License is included in this file.
+//
+//
+
+package recovery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+type loginReq struct {
+	User     string            `json:"user"`
+	Password string            `json:"password"`
+	Phone    string            `json:"phone"`
+	Extra    map[string]string `json:"extra"`
+}
+
+// recoverReport panics in fn, and returns the report of the recovered panic.
+func recoverReport(r *Reporter, ctx context.Context, fn func()) (rep *Report) {
+	defer func() {
+		if e := recover(); e != nil {
+			rep = r.newReport(ctx, e)
+		}
+	}()
+	fn()
+	return nil
+}
+
+func panicA() { panic("a") }
+
+func panicB() { panic("b") }
+
+func TestReporter_Signature(t *testing.T) {
+	Convey("TestReporter_Signature", t, func() {
+		r := NewReporter(Config{StackDepth: 4})
+		var reps []*Report
+		for _, fn := range []func(){panicA, panicA, panicB} {
+			reps = append(reps, recoverReport(r, context.Background(), fn))
+		}
+		a1, a2, b := reps[0], reps[1], reps[2]
+		So(a1.Stack, ShouldHaveLength, 4)
+		So(a1.Stack[0], ShouldStartWith, "trpc.group/trpc-go/trpc-filter/recovery.panicA ")
+		So(a1.Panic, ShouldEqual, "a")
+		So(a1.Side, ShouldEqual, SideServer)
+		So(a1.Signature, ShouldEqual, a2.Signature)
+		So(a1.Signature, ShouldNotEqual, b.Signature)
+		So(a2.Count, ShouldEqual, 2)
+		So(b.Count, ShouldEqual, 1)
+	})
+}
+
+func TestReporter_Dedup(t *testing.T) {
+	Convey("TestReporter_Dedup", t, func() {
+		r := NewReporter(Config{Dedup: DedupConfig{Interval: 60, Burst: 1}})
+		ctx := context.Background()
+		var reps []*Report
+		for i, fn := range []func(){panicA, panicA, panicA, panicB, panicA} {
+			if i == 4 {
+				// The suppressed count is reported in the next window.
+				r.signatures[reps[0].Signature].window = time.Now().Add(-time.Minute)
+			}
+			reps = append(reps, recoverReport(r, ctx, fn))
+		}
+		So(reps[0], ShouldNotBeNil)
+		So(reps[1], ShouldBeNil)
+		So(reps[2], ShouldBeNil)
+		So(reps[3], ShouldNotBeNil)
+
+		stats := r.Stats()
+		So(stats, ShouldHaveLength, 2)
+		So(stats[0].Signature, ShouldEqual, reps[0].Signature)
+		So(stats[0].Count, ShouldEqual, 4)
+		So(stats[0].Suppressed, ShouldEqual, 2)
+		So(stats[1].Count, ShouldEqual, 1)
+
+		rep := reps[4]
+		So(rep, ShouldNotBeNil)
+		So(rep.Count, ShouldEqual, 4)
+		So(rep.Suppressed, ShouldEqual, 2)
+	})
+}
+
+func TestReporter_Redact(t *testing.T) {
+	Convey("TestReporter_Redact", t, func() {
+		r := NewReporter(Config{IncludeRequest: true, IncludeMetadata: true, RedactKeys: []string{"phone"}, MaxRequestLen: 200})
+		ctx, msg := codec.WithNewMessage(context.Background())
+		msg.WithClientRPCName("/trpc.app.server.service/Login")
+		msg.WithCalleeServiceName("trpc.app.server.service")
+		msg.WithClientMetaData(codec.MetaData{"Authorization": []byte("Bearer x"), "env": []byte("test")})
+		req := &loginReq{User: "alice", Password: "p", Phone: "13812345678", Extra: map[string]string{"access_token": "t"}}
+
+		rep := recoverReport(r, withPanicInfo(ctx, SideClient, req), panicA)
+		So(rep.Side, ShouldEqual, SideClient)
+		So(rep.RPCName, ShouldEqual, "/trpc.app.server.service/Login")
+		So(rep.Callee, ShouldEqual, "trpc.app.server.service")
+		So(rep.Metadata, ShouldResemble, map[string]string{"Authorization": redacted, "env": "test"})
+		So(rep.Request, ShouldContainSubstring, `"user":"alice"`)
+		for _, s := range []string{"13812345678", `"p"`, `"t"`} {
+			So(rep.Request, ShouldNotContainSubstring, s)
+		}
+
+		// Requests and metadata are not included by default.
+		rep = recoverReport(NewReporter(Config{}), withPanicInfo(ctx, SideClient, req), panicA)
+		So(rep.Request, ShouldBeEmpty)
+		So(rep.Metadata, ShouldBeNil)
+
+		r = NewReporter(Config{IncludeRequest: true, MaxRequestLen: 10})
+		rep = recoverReport(r, withPanicInfo(ctx, SideServer, strings.Repeat("x", 100)), panicA)
+		So(rep.Request, ShouldEqual, `"xxxxxxxxx...(truncated)`)
+		rep = recoverReport(r, withPanicInfo(ctx, SideServer, make(chan int)), panicA)
+		So(rep.Request, ShouldStartWith, "<chan int")
+	})
+}